@@ -0,0 +1,197 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"discord-github-bot/internal/database"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+const (
+	auditDefaultLimit = 20
+	auditMaxLimit     = 50
+)
+
+// auditedCommands are the commands /gh-audit can filter by.
+var auditedCommands = []string{
+	"gh-issue-create",
+	"gh-issue-new",
+	messageIssueCommand,
+	"gh-issue-close",
+	"gh-issue-comment",
+	"gh-project-add-issue",
+	"gh-set-repo",
+	"gh-set-project",
+	"gh-subscribe",
+	"gh-unsubscribe",
+	"gh-forum-sync",
+	"gh-unfurl",
+	"gh-admin",
+}
+
+// modalCommands maps a modal's CustomID prefix to the command that opened it.
+var modalCommands = map[string]string{
+	issueModalCustomIDPrefix:   "gh-issue-new",
+	messageIssueCustomIDPrefix: messageIssueCommand,
+}
+
+// audit records the outcome of a mutating command. entry only needs the
+// target fields; who, where and which command are filled in from i unless
+// entry.Command is already set. Failing to write the log never fails the
+// command itself.
+func (b *Bot) audit(i *discordgo.InteractionCreate, entry *database.AuditEntry, err error) {
+	if entry.Command == "" {
+		entry.Command = auditCommand(i)
+	}
+
+	b.auditAs(i.GuildID, i.ChannelID, invokingUser(i).ID, entry, err)
+}
+
+// auditCommand names the command behind i. Modal submits and components carry
+// no command name, so they are named after the prefix of their CustomID.
+func auditCommand(i *discordgo.InteractionCreate) string {
+	var customID string
+	switch i.Type {
+	case discordgo.InteractionApplicationCommand:
+		return i.ApplicationCommandData().Name
+	case discordgo.InteractionModalSubmit:
+		customID = i.ModalSubmitData().CustomID
+	case discordgo.InteractionMessageComponent:
+		customID = i.MessageComponentData().CustomID
+	}
+
+	prefix, _, _ := strings.Cut(customID, ":")
+	if command, ok := modalCommands[prefix+":"]; ok {
+		return command
+	}
+	return prefix
+}
+
+// auditAs records the outcome of a GitHub write discordID caused without an
+// interaction, such as replying in a synced forum post. entry.Command must be set.
+func (b *Bot) auditAs(guildID, channelID, discordID string, entry *database.AuditEntry, err error) {
+	entry.GuildID = guildID
+	entry.ChannelID = channelID
+	entry.Success = err == nil
+	if err != nil {
+		entry.Error = err.Error()
+	}
+
+	entry.DiscordID = discordID
+
+	if user, userErr := b.db.GetUser(entry.DiscordID); userErr == nil && user != nil {
+		entry.GitHubLogin = user.GitHubUsername
+	}
+
+	if err := b.db.RecordAudit(entry); err != nil {
+		log.Printf("Failed to record audit entry for %s: %v", entry.Command, err)
+	}
+}
+
+func (b *Bot) handleAudit(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if !guildInstalled(i) {
+		b.respondError(s, i, "This command can only be used in a server")
+		return
+	}
+
+	options := i.ApplicationCommandData().Options
+	filter := database.AuditFilter{
+		GuildID:     i.GuildID,
+		Command:     b.getStringOption(options, "command"),
+		Repo:        b.getStringOption(options, "repo"),
+		IssueNumber: b.getIntOption(options, "number"),
+		Limit:       b.getIntOption(options, "limit"),
+	}
+
+	for _, opt := range options {
+		if opt.Name == "user" {
+			filter.DiscordID = opt.UserValue(nil).ID
+		}
+	}
+
+	if filter.Limit <= 0 {
+		filter.Limit = auditDefaultLimit
+	}
+	if filter.Limit > auditMaxLimit {
+		filter.Limit = auditMaxLimit
+	}
+
+	entries, err := b.db.ListAuditEntries(filter)
+	if err != nil {
+		log.Printf("Failed to list audit entries: %v", err)
+		b.respondError(s, i, "Failed to read the audit log")
+		return
+	}
+
+	if len(entries) == 0 {
+		b.respondEphemeral(s, i, "No audit log entries match those filters.")
+		return
+	}
+
+	var response strings.Builder
+	response.WriteString("**Audit log:**\n\n")
+
+	for n, entry := range entries {
+		line := formatAuditEntry(entry)
+		// Discord rejects messages over 2000 characters.
+		if response.Len()+len(line) > 1900 {
+			response.WriteString(fmt.Sprintf("_…and %d more_\n", len(entries)-n))
+			break
+		}
+		response.WriteString(line)
+	}
+
+	b.respondEphemeral(s, i, response.String())
+}
+
+func formatAuditEntry(entry *database.AuditEntry) string {
+	status := "✅"
+	if !entry.Success {
+		status = "❌"
+	}
+
+	actor := fmt.Sprintf("<@%s>", entry.DiscordID)
+	if entry.GitHubLogin != "" {
+		actor += fmt.Sprintf(" (%s)", entry.GitHubLogin)
+	}
+
+	target := entry.Repo
+	if entry.IssueNumber != 0 {
+		target += fmt.Sprintf("#%d", entry.IssueNumber)
+	}
+	if entry.Project != "" {
+		if target != "" {
+			target += " → "
+		}
+		target += "project " + entry.Project
+	}
+
+	line := fmt.Sprintf("%s <t:%d:f> %s `/%s` %s in <#%s>", status, entry.CreatedAt.Unix(), actor, entry.Command, target, entry.ChannelID)
+	if entry.Details != "" {
+		line += " — " + entry.Details
+	}
+	if entry.Error != "" {
+		line += fmt.Sprintf(" — error: %s", truncate(entry.Error, 100))
+	}
+
+	return line + "\n"
+}
+
+func truncate(s string, max int) string {
+	runes := []rune(s)
+	if len(runes) <= max {
+		return s
+	}
+	return string(runes[:max]) + "…"
+}
+
+func auditCommandChoices() []*discordgo.ApplicationCommandOptionChoice {
+	choices := make([]*discordgo.ApplicationCommandOptionChoice, 0, len(auditedCommands))
+	for _, name := range auditedCommands {
+		choices = append(choices, &discordgo.ApplicationCommandOptionChoice{Name: name, Value: name})
+	}
+	return choices
+}
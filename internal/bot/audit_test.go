@@ -0,0 +1,90 @@
+package bot
+
+import (
+	"testing"
+
+	"discord-github-bot/internal/database"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestAuditCommand(t *testing.T) {
+	tests := []struct {
+		name        string
+		interaction *discordgo.Interaction
+		want        string
+	}{
+		{
+			name: "slash command",
+			interaction: &discordgo.Interaction{
+				Type: discordgo.InteractionApplicationCommand,
+				Data: discordgo.ApplicationCommandInteractionData{Name: "gh-issue-close"},
+			},
+			want: "gh-issue-close",
+		},
+		{
+			name: "issue form",
+			interaction: &discordgo.Interaction{
+				Type: discordgo.InteractionModalSubmit,
+				Data: discordgo.ModalSubmitInteractionData{CustomID: issueModalCustomIDPrefix + "octo/repo"},
+			},
+			want: "gh-issue-new",
+		},
+		{
+			name: "message issue form",
+			interaction: &discordgo.Interaction{
+				Type: discordgo.InteractionModalSubmit,
+				Data: discordgo.ModalSubmitInteractionData{CustomID: messageIssueCustomIDPrefix + "123:octo/repo"},
+			},
+			want: messageIssueCommand,
+		},
+		{
+			name: "unknown form",
+			interaction: &discordgo.Interaction{
+				Type: discordgo.InteractionModalSubmit,
+				Data: discordgo.ModalSubmitInteractionData{CustomID: "other:1"},
+			},
+			want: "other",
+		},
+		{
+			name: "component",
+			interaction: &discordgo.Interaction{
+				Type: discordgo.InteractionMessageComponent,
+				Data: discordgo.MessageComponentInteractionData{CustomID: listingCustomIDPrefix + "abc:2"},
+			},
+			want: "list",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := auditCommand(&discordgo.InteractionCreate{Interaction: tt.interaction}); got != tt.want {
+				t.Errorf("auditCommand() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestAuditModalSubmit(t *testing.T) {
+	b := newTestBot(t)
+
+	i := &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{
+		Type:      discordgo.InteractionModalSubmit,
+		GuildID:   "1",
+		ChannelID: "2",
+		Member:    &discordgo.Member{User: &discordgo.User{ID: "3"}},
+		Data:      discordgo.ModalSubmitInteractionData{CustomID: issueModalCustomIDPrefix + "octo/repo"},
+	}}
+	b.audit(i, &database.AuditEntry{Repo: "octo/repo", IssueNumber: 7}, nil)
+
+	entries, err := b.db.ListAuditEntries(database.AuditFilter{GuildID: "1"})
+	if err != nil {
+		t.Fatalf("ListAuditEntries: %v", err)
+	}
+	if len(entries) != 1 {
+		t.Fatalf("got %d audit entries, want 1", len(entries))
+	}
+	if got := entries[0]; got.Command != "gh-issue-new" || got.DiscordID != "3" || !got.Success {
+		t.Errorf("audit entry = %+v, want a successful gh-issue-new by 3", got)
+	}
+}
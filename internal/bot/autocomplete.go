@@ -0,0 +1,265 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strconv"
+	"strings"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/google/go-github/v57/github"
+)
+
+const (
+	// suggestionTTL is how long fetched suggestions are reused for a user, so
+	// typing doesn't cost a GitHub request per keystroke.
+	suggestionTTL = time.Minute
+
+	// maxSuggestions is Discord's limit on autocomplete choices.
+	maxSuggestions = 25
+
+	// maxChoiceNameLength is Discord's limit on a choice's display name.
+	maxChoiceNameLength = 100
+)
+
+// suggestion is a candidate autocomplete choice before filtering.
+type suggestion struct {
+	name  string
+	value interface{}
+}
+
+type cachedSuggestions struct {
+	suggestions []suggestion
+	expiresAt   time.Time
+}
+
+func (b *Bot) handleAutocomplete(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	data := i.ApplicationCommandData()
+
+	var focused *discordgo.ApplicationCommandInteractionDataOption
+	for _, opt := range data.Options {
+		if opt.Focused {
+			focused = opt
+			break
+		}
+	}
+
+	var choices []*discordgo.ApplicationCommandOptionChoice
+	if focused != nil {
+		suggestions, err := b.suggest(s, i, focused.Name)
+		if err != nil {
+			log.Printf("Failed to load %s suggestions for %s: %v", focused.Name, data.Name, err)
+		}
+		choices = filterSuggestions(suggestions, fmt.Sprint(focused.Value))
+	}
+
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionApplicationCommandAutocompleteResult,
+		Data: &discordgo.InteractionResponseData{Choices: choices},
+	})
+	if err != nil {
+		log.Printf("Failed to respond to autocomplete: %v", err)
+	}
+}
+
+// suggest returns every candidate for the option being typed. Users who
+// haven't linked GitHub get no suggestions.
+func (b *Bot) suggest(s *discordgo.Session, i *discordgo.InteractionCreate, option string) ([]suggestion, error) {
+	userID := invokingUser(i).ID
+	options := i.ApplicationCommandData().Options
+
+	switch option {
+	case "repo":
+		return b.cachedSuggestions(userID, "repo", func() ([]suggestion, error) {
+			return b.suggestRepos(userID)
+		})
+	case "org":
+		return b.cachedSuggestions(userID, "org", func() ([]suggestion, error) {
+			return b.suggestOrgs(userID)
+		})
+	case "number", "issue-number":
+		repo := b.getStringOption(options, "repo")
+		if repo == "" {
+			if settings, err := b.getEffectiveSettings(s, i); err == nil {
+				repo = settings.DefaultRepo
+			}
+		}
+		owner, repoName, found := strings.Cut(repo, "/")
+		if !found {
+			return nil, nil
+		}
+		return b.cachedSuggestions(userID, "issues:"+strings.ToLower(repo), func() ([]suggestion, error) {
+			return b.suggestIssues(userID, owner, repoName)
+		})
+	case "project-number":
+		org := b.getStringOption(options, "org")
+		if org == "" {
+			if settings, err := b.getEffectiveSettings(s, i); err == nil {
+				if settings.DefaultProject != "" {
+					org, _ = b.parseProjectValue(settings.DefaultProject)
+				}
+				if org == "" {
+					org, _, _ = strings.Cut(settings.DefaultRepo, "/")
+				}
+			}
+		}
+		if org == "" {
+			return nil, nil
+		}
+		return b.cachedSuggestions(userID, "projects:"+strings.ToLower(org), func() ([]suggestion, error) {
+			return b.suggestProjects(userID, org)
+		})
+	}
+
+	return nil, nil
+}
+
+// cachedSuggestions returns userID's suggestions for key, calling load when
+// they are missing or older than suggestionTTL.
+func (b *Bot) cachedSuggestions(userID, key string, load func() ([]suggestion, error)) ([]suggestion, error) {
+	cacheKey := userID + "/" + key
+	if value, ok := b.suggestions.Load(cacheKey); ok {
+		cached := value.(*cachedSuggestions)
+		if time.Now().Before(cached.expiresAt) {
+			return cached.suggestions, nil
+		}
+		b.suggestions.Delete(cacheKey)
+	}
+
+	suggestions, err := load()
+	if err != nil {
+		return nil, err
+	}
+
+	now := time.Now()
+	b.suggestions.Range(func(key, value any) bool {
+		if value.(*cachedSuggestions).expiresAt.Before(now) {
+			b.suggestions.Delete(key)
+		}
+		return true
+	})
+
+	b.suggestions.Store(cacheKey, &cachedSuggestions{
+		suggestions: suggestions,
+		expiresAt:   now.Add(suggestionTTL),
+	})
+
+	return suggestions, nil
+}
+
+func (b *Bot) suggestRepos(userID string) ([]suggestion, error) {
+	client, err := b.oauth.GetGitHubClient(userID)
+	if err != nil {
+		return nil, nil
+	}
+
+	repos, _, err := client.Repositories.ListByAuthenticatedUser(context.Background(), &github.RepositoryListByAuthenticatedUserOptions{
+		Sort:        "pushed",
+		ListOptions: github.ListOptions{PerPage: 100},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions := make([]suggestion, 0, len(repos))
+	for _, repo := range repos {
+		suggestions = append(suggestions, suggestion{name: repo.GetFullName(), value: repo.GetFullName()})
+	}
+	return suggestions, nil
+}
+
+func (b *Bot) suggestOrgs(userID string) ([]suggestion, error) {
+	client, err := b.oauth.GetGitHubClient(userID)
+	if err != nil {
+		return nil, nil
+	}
+
+	orgs, _, err := client.Organizations.List(context.Background(), "", &github.ListOptions{PerPage: 100})
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions := make([]suggestion, 0, len(orgs))
+	for _, org := range orgs {
+		suggestions = append(suggestions, suggestion{name: org.GetLogin(), value: org.GetLogin()})
+	}
+	return suggestions, nil
+}
+
+func (b *Bot) suggestIssues(userID, owner, repoName string) ([]suggestion, error) {
+	client, err := b.oauth.GetGitHubClient(userID)
+	if err != nil {
+		return nil, nil
+	}
+
+	issues, _, err := client.Issues.ListByRepo(context.Background(), owner, repoName, &github.IssueListByRepoOptions{
+		State:       "open",
+		Sort:        "updated",
+		ListOptions: github.ListOptions{PerPage: 100},
+	})
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions := make([]suggestion, 0, len(issues))
+	for _, issue := range issues {
+		if issue.IsPullRequest() {
+			continue
+		}
+		suggestions = append(suggestions, suggestion{
+			name:  fmt.Sprintf("#%d %s", issue.GetNumber(), issue.GetTitle()),
+			value: issue.GetNumber(),
+		})
+	}
+	return suggestions, nil
+}
+
+func (b *Bot) suggestProjects(userID, org string) ([]suggestion, error) {
+	accessToken, err := b.oauth.GetGitHubToken(userID)
+	if err != nil {
+		return nil, nil
+	}
+
+	projects, _, err := b.githubREST.ListProjects(org, accessToken, 100, "is:open", nil)
+	if err != nil {
+		return nil, err
+	}
+
+	suggestions := make([]suggestion, 0, len(projects.Projects))
+	for _, project := range projects.Projects {
+		suggestions = append(suggestions, suggestion{
+			name:  fmt.Sprintf("#%d %s", project.Number, project.Title),
+			value: project.Number,
+		})
+	}
+	return suggestions, nil
+}
+
+// filterSuggestions returns up to maxSuggestions choices whose name contains
+// typed, case-insensitively. A typed number also matches by prefix, so "12"
+// finds #12 and #123 before titles that merely contain "12".
+func filterSuggestions(suggestions []suggestion, typed string) []*discordgo.ApplicationCommandOptionChoice {
+	typed = strings.ToLower(strings.TrimPrefix(strings.TrimSpace(typed), "#"))
+	_, numeric := strconv.Atoi(typed)
+
+	var prefixed, contained []*discordgo.ApplicationCommandOptionChoice
+	for _, sug := range suggestions {
+		name := strings.ToLower(sug.name)
+		choice := &discordgo.ApplicationCommandOptionChoice{Name: truncate(sug.name, maxChoiceNameLength-1), Value: sug.value}
+
+		switch {
+		case numeric == nil && strings.HasPrefix(name, "#"+typed):
+			prefixed = append(prefixed, choice)
+		case strings.Contains(name, typed):
+			contained = append(contained, choice)
+		}
+	}
+
+	choices := append(prefixed, contained...)
+	if len(choices) > maxSuggestions {
+		choices = choices[:maxSuggestions]
+	}
+	return choices
+}
@@ -3,49 +3,112 @@ package bot
 import (
 	"fmt"
 	"log"
+	"regexp"
+	"strings"
+	"sync"
 
 	"discord-github-bot/internal/config"
 	"discord-github-bot/internal/database"
-	"discord-github-bot/internal/oauth"
+	"discord-github-bot/internal/github/app"
 	"discord-github-bot/internal/github/rest"
+	"discord-github-bot/internal/oauth"
 
 	"github.com/bwmarrin/discordgo"
+	"github.com/google/go-github/v57/github"
 )
 
 type Bot struct {
-	config      *config.Config
-	db          *database.Database
-	oauth       *oauth.Server
-	session     *discordgo.Session
-	commands    []*discordgo.ApplicationCommand
+	config     *config.Config
+	db         database.Store
+	oauth      *oauth.Server
+	session    *discordgo.Session
+	commands   []*discordgo.ApplicationCommand
 	githubREST *rest.GitHubRESTClient
+	githubApp  *app.InstallationAuth
+
+	// deferred maps interaction IDs to how they were deferred.
+	deferred sync.Map
+	// listings maps listing IDs to paginated results whose buttons are still live.
+	listings sync.Map
+	// suggestions caches autocomplete candidates per user.
+	suggestions sync.Map
+	// unfurlWindows maps channel IDs to their issue preview rate limit windows.
+	unfurlWindows sync.Map
+
+	// issueURLPattern matches issue and pull request URLs on the configured GitHub host.
+	issueURLPattern *regexp.Regexp
+	// permalinkPattern matches code permalinks on the configured GitHub host.
+	permalinkPattern *regexp.Regexp
 }
 
-func New(cfg *config.Config, db *database.Database, oauthServer *oauth.Server) (*Bot, error) {
+func New(cfg *config.Config, db database.Store, oauthServer *oauth.Server, githubApp *app.InstallationAuth) (*Bot, error) {
 	session, err := discordgo.New("Bot " + cfg.DiscordBotToken)
 	if err != nil {
 		return nil, err
 	}
 
+	githubURL := strings.TrimSuffix(cfg.GitHubURL, "/")
 	bot := &Bot{
-		config:  cfg,
-		db:      db,
-		oauth:   oauthServer,
-		session: session,
-		githubREST: rest.NewGitHubRESTClient(),
+		config:     cfg,
+		db:         db,
+		oauth:      oauthServer,
+		session:    session,
+		githubREST: rest.NewGitHubRESTClient(cfg.GitHubAPIURL),
+		githubApp:  githubApp,
+
+		issueURLPattern:  regexp.MustCompile(regexp.QuoteMeta(githubURL) + `/([\w.-]+)/([\w.-]+)/(?:issues|pull)/(\d+)`),
+		permalinkPattern: regexp.MustCompile(regexp.QuoteMeta(githubURL) + permalinkPath),
+	}
+
+	if cfg.DiscordMessageContent {
+		session.Identify.Intents |= discordgo.IntentsMessageContent
 	}
 
 	bot.registerCommands()
 	session.AddHandler(bot.handleInteraction)
+	session.AddHandler(bot.handleMessageCreate)
+	session.AddHandler(bot.handleThreadUpdate)
+	session.AddHandler(bot.handleThreadDelete)
 
 	return bot, nil
 }
 
 func (b *Bot) registerCommands() {
+	// Only admin commands are hidden from members by default. Other restricted
+	// commands stay visible so authorize can apply the guild's /gh-admin policy,
+	// which may allow roles without the default permission.
+	manageServer := int64(adminPermission)
+
+	// Commands work in servers, DMs with the bot and (when the user has
+	// installed the app) other DMs, unless they only make sense in a server.
+	allContexts := []discordgo.InteractionContextType{
+		discordgo.InteractionContextGuild,
+		discordgo.InteractionContextBotDM,
+		discordgo.InteractionContextPrivateChannel,
+	}
+	allInstalls := []discordgo.ApplicationIntegrationType{
+		discordgo.ApplicationIntegrationGuildInstall,
+		discordgo.ApplicationIntegrationUserInstall,
+	}
+	guildContexts := []discordgo.InteractionContextType{discordgo.InteractionContextGuild}
+	guildInstalls := []discordgo.ApplicationIntegrationType{discordgo.ApplicationIntegrationGuildInstall}
+
 	b.commands = []*discordgo.ApplicationCommand{
 		{
 			Name:        "gh-auth",
 			Description: "Authenticate with GitHub to link your account",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "method",
+					Description: "How to sign in (device works without opening the bot's website)",
+					Required:    false,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "browser", Value: "browser"},
+						{Name: "device", Value: "device"},
+					},
+				},
+			},
 		},
 		{
 			Name:        "gh-unauth",
@@ -53,19 +116,32 @@ func (b *Bot) registerCommands() {
 		},
 		{
 			Name:        "gh-set-repo",
-			Description: "Set the default repository for this channel",
+			Description: "Set the default repository for this channel, category or server",
 			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:         discordgo.ApplicationCommandOptionString,
+					Name:         "repo",
+					Description:  "Repository in format: owner/repo",
+					Required:     true,
+					Autocomplete: true,
+				},
 				{
 					Type:        discordgo.ApplicationCommandOptionString,
-					Name:        "repo",
-					Description: "Repository in format: owner/repo",
-					Required:    true,
+					Name:        "scope",
+					Description: "Where to apply the default (default: this channel, or your personal defaults in DMs)",
+					Required:    false,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "channel", Value: "channel"},
+						{Name: "category", Value: "category"},
+						{Name: "server", Value: "server"},
+						{Name: "personal", Value: "personal"},
+					},
 				},
 			},
 		},
 		{
 			Name:        "gh-set-project",
-			Description: "Set the default project for this channel",
+			Description: "Set the default project for this channel, category or server",
 			Options: []*discordgo.ApplicationCommandOption{
 				{
 					Type:        discordgo.ApplicationCommandOptionString,
@@ -73,6 +149,170 @@ func (b *Bot) registerCommands() {
 					Description: "Project number",
 					Required:    true,
 				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "scope",
+					Description: "Where to apply the default (default: this channel, or your personal defaults in DMs)",
+					Required:    false,
+					Choices: []*discordgo.ApplicationCommandOptionChoice{
+						{Name: "channel", Value: "channel"},
+						{Name: "category", Value: "category"},
+						{Name: "server", Value: "server"},
+						{Name: "personal", Value: "personal"},
+					},
+				},
+			},
+		},
+		{
+			Name:        "gh-settings",
+			Description: "Show the default repository and project for this channel and where they come from",
+		},
+		{
+			Name:                     "gh-admin",
+			Description:              "Configure who can use the bot's commands in this server",
+			DefaultMemberPermissions: &manageServer,
+			Contexts:                 &guildContexts,
+			IntegrationTypes:         &guildInstalls,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "allow",
+					Description: "Allow a role or permission to use a group of commands",
+					Options:     permissionRuleOptions(),
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "revoke",
+					Description: "Remove a role or permission previously allowed",
+					Options:     permissionRuleOptions(),
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "reset",
+					Description: "Restore the default policy for a group of commands",
+					Options:     []*discordgo.ApplicationCommandOption{actionOption()},
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionSubCommand,
+					Name:        "list",
+					Description: "Show who can use each group of commands",
+				},
+			},
+		},
+		{
+			Name:                     "gh-audit",
+			Description:              "Show GitHub writes and settings changes made through the bot in this server",
+			DefaultMemberPermissions: &manageServer,
+			Contexts:                 &guildContexts,
+			IntegrationTypes:         &guildInstalls,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionUser,
+					Name:        "user",
+					Description: "Only show actions by this member",
+					Required:    false,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "command",
+					Description: "Only show this command",
+					Required:    false,
+					Choices:     auditCommandChoices(),
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "repo",
+					Description: "Only show actions on this repository (owner/repo)",
+					Required:    false,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "number",
+					Description: "Only show actions on this issue number",
+					Required:    false,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionInteger,
+					Name:        "limit",
+					Description: "Number of entries to show (default 20, max 50)",
+					Required:    false,
+				},
+			},
+		},
+		{
+			Name:             "gh-subscribe",
+			Description:      "Post GitHub events for a repository to this channel",
+			Contexts:         &guildContexts,
+			IntegrationTypes: &guildInstalls,
+			Options: append([]*discordgo.ApplicationCommandOption{
+				{
+					Type:         discordgo.ApplicationCommandOptionString,
+					Name:         "repo",
+					Description:  "Repository in format: owner/repo",
+					Required:     true,
+					Autocomplete: true,
+				},
+			}, subscriptionFilterOptions()...),
+		},
+		{
+			Name:             "gh-unsubscribe",
+			Description:      "Stop posting GitHub events for a repository to this channel",
+			Contexts:         &guildContexts,
+			IntegrationTypes: &guildInstalls,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "repo",
+					Description: "Repository in format: owner/repo",
+					Required:    true,
+				},
+				{
+					Type:        discordgo.ApplicationCommandOptionString,
+					Name:        "events",
+					Description: "Comma-separated events to stop posting (default: the whole subscription)",
+					Required:    false,
+				},
+			},
+		},
+		{
+			Name:             "gh-subscriptions",
+			Description:      "Show which GitHub events are posted to this channel",
+			Contexts:         &guildContexts,
+			IntegrationTypes: &guildInstalls,
+		},
+		{
+			Name:             "gh-forum-sync",
+			Description:      "Link issues created from forum posts to the post and mirror replies and closing",
+			Contexts:         &guildContexts,
+			IntegrationTypes: &guildInstalls,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+					Name:        "enabled",
+					Description: "Whether new issues from posts in the forum are linked",
+					Required:    true,
+				},
+				{
+					Type:         discordgo.ApplicationCommandOptionChannel,
+					Name:         "forum",
+					Description:  "Forum channel (default: the forum this post is in)",
+					Required:     false,
+					ChannelTypes: []discordgo.ChannelType{discordgo.ChannelTypeGuildForum},
+				},
+			},
+		},
+		{
+			Name:             "gh-unfurl",
+			Description:      "Preview issues, pull requests and code permalinks posted in this channel",
+			Contexts:         &guildContexts,
+			IntegrationTypes: &guildInstalls,
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:        discordgo.ApplicationCommandOptionBoolean,
+					Name:        "enabled",
+					Description: "Whether references are previewed, using your GitHub account",
+					Required:    true,
+				},
 			},
 		},
 		{
@@ -88,26 +328,45 @@ func (b *Bot) registerCommands() {
 				{
 					Type:        discordgo.ApplicationCommandOptionString,
 					Name:        "body",
-					Description: "Issue description",
+					Description: "Issue description (leave out to write a multi-line one in a form)",
 					Required:    false,
 				},
 				{
-					Type:        discordgo.ApplicationCommandOptionString,
-					Name:        "repo",
-					Description: "Repository (overrides channel default)",
-					Required:    false,
+					Type:         discordgo.ApplicationCommandOptionString,
+					Name:         "repo",
+					Description:  "Repository (overrides channel default)",
+					Required:     false,
+					Autocomplete: true,
 				},
 			},
 		},
+		{
+			Name:        "gh-issue-new",
+			Description: "Create a new GitHub issue using a form with a multi-line description",
+			Options: []*discordgo.ApplicationCommandOption{
+				{
+					Type:         discordgo.ApplicationCommandOptionString,
+					Name:         "repo",
+					Description:  "Repository (overrides channel default)",
+					Required:     false,
+					Autocomplete: true,
+				},
+			},
+		},
+		{
+			Name: messageIssueCommand,
+			Type: discordgo.MessageApplicationCommand,
+		},
 		{
 			Name:        "gh-issue-list",
 			Description: "List GitHub issues",
 			Options: []*discordgo.ApplicationCommandOption{
 				{
-					Type:        discordgo.ApplicationCommandOptionString,
-					Name:        "repo",
-					Description: "Repository (overrides channel default)",
-					Required:    false,
+					Type:         discordgo.ApplicationCommandOptionString,
+					Name:         "repo",
+					Description:  "Repository (overrides channel default)",
+					Required:     false,
+					Autocomplete: true,
 				},
 				{
 					Type:        discordgo.ApplicationCommandOptionString,
@@ -133,16 +392,18 @@ func (b *Bot) registerCommands() {
 			Description: "View a specific GitHub issue",
 			Options: []*discordgo.ApplicationCommandOption{
 				{
-					Type:        discordgo.ApplicationCommandOptionInteger,
-					Name:        "number",
-					Description: "Issue number",
-					Required:    true,
+					Type:         discordgo.ApplicationCommandOptionInteger,
+					Name:         "number",
+					Description:  "Issue number",
+					Required:     true,
+					Autocomplete: true,
 				},
 				{
-					Type:        discordgo.ApplicationCommandOptionString,
-					Name:        "repo",
-					Description: "Repository (overrides channel default)",
-					Required:    false,
+					Type:         discordgo.ApplicationCommandOptionString,
+					Name:         "repo",
+					Description:  "Repository (overrides channel default)",
+					Required:     false,
+					Autocomplete: true,
 				},
 			},
 		},
@@ -151,10 +412,11 @@ func (b *Bot) registerCommands() {
 			Description: "Close a GitHub issue",
 			Options: []*discordgo.ApplicationCommandOption{
 				{
-					Type:        discordgo.ApplicationCommandOptionInteger,
-					Name:        "number",
-					Description: "Issue number",
-					Required:    true,
+					Type:         discordgo.ApplicationCommandOptionInteger,
+					Name:         "number",
+					Description:  "Issue number",
+					Required:     true,
+					Autocomplete: true,
 				},
 				{
 					Type:        discordgo.ApplicationCommandOptionString,
@@ -168,10 +430,11 @@ func (b *Bot) registerCommands() {
 					},
 				},
 				{
-					Type:        discordgo.ApplicationCommandOptionString,
-					Name:        "repo",
-					Description: "Repository (overrides channel default)",
-					Required:    false,
+					Type:         discordgo.ApplicationCommandOptionString,
+					Name:         "repo",
+					Description:  "Repository (overrides channel default)",
+					Required:     false,
+					Autocomplete: true,
 				},
 			},
 		},
@@ -180,10 +443,11 @@ func (b *Bot) registerCommands() {
 			Description: "Comment on a GitHub issue",
 			Options: []*discordgo.ApplicationCommandOption{
 				{
-					Type:        discordgo.ApplicationCommandOptionInteger,
-					Name:        "number",
-					Description: "Issue number",
-					Required:    true,
+					Type:         discordgo.ApplicationCommandOptionInteger,
+					Name:         "number",
+					Description:  "Issue number",
+					Required:     true,
+					Autocomplete: true,
 				},
 				{
 					Type:        discordgo.ApplicationCommandOptionString,
@@ -192,10 +456,11 @@ func (b *Bot) registerCommands() {
 					Required:    true,
 				},
 				{
-					Type:        discordgo.ApplicationCommandOptionString,
-					Name:        "repo",
-					Description: "Repository (overrides channel default)",
-					Required:    false,
+					Type:         discordgo.ApplicationCommandOptionString,
+					Name:         "repo",
+					Description:  "Repository (overrides channel default)",
+					Required:     false,
+					Autocomplete: true,
 				},
 			},
 		},
@@ -204,16 +469,18 @@ func (b *Bot) registerCommands() {
 			Description: "List items in a GitHub project",
 			Options: []*discordgo.ApplicationCommandOption{
 				{
-					Type:        discordgo.ApplicationCommandOptionInteger,
-					Name:        "project-number",
-					Description: "The number of the project",
-					Required:    false,
+					Type:         discordgo.ApplicationCommandOptionInteger,
+					Name:         "project-number",
+					Description:  "The number of the project",
+					Required:     false,
+					Autocomplete: true,
 				},
 				{
-					Type:        discordgo.ApplicationCommandOptionString,
-					Name:        "org",
-					Description: "Organization name (overrides channel default)",
-					Required:    false,
+					Type:         discordgo.ApplicationCommandOptionString,
+					Name:         "org",
+					Description:  "Organization name (overrides channel default)",
+					Required:     false,
+					Autocomplete: true,
 				},
 				{
 					Type:        discordgo.ApplicationCommandOptionString,
@@ -228,10 +495,11 @@ func (b *Bot) registerCommands() {
 			Description: "List all GitHub projects in an organization",
 			Options: []*discordgo.ApplicationCommandOption{
 				{
-					Type:        discordgo.ApplicationCommandOptionString,
-					Name:        "org",
-					Description: "Organization name (overrides channel default)",
-					Required:    false,
+					Type:         discordgo.ApplicationCommandOptionString,
+					Name:         "org",
+					Description:  "Organization name (overrides channel default)",
+					Required:     false,
+					Autocomplete: true,
 				},
 				{
 					Type:        discordgo.ApplicationCommandOptionString,
@@ -246,32 +514,43 @@ func (b *Bot) registerCommands() {
 			Description: "Add an existing issue to a GitHub project",
 			Options: []*discordgo.ApplicationCommandOption{
 				{
-					Type:        discordgo.ApplicationCommandOptionInteger,
-					Name:        "issue-number",
-					Description: "Issue number to add",
-					Required:    true,
+					Type:         discordgo.ApplicationCommandOptionInteger,
+					Name:         "issue-number",
+					Description:  "Issue number to add",
+					Required:     true,
+					Autocomplete: true,
 				},
 				{
-					Type:        discordgo.ApplicationCommandOptionInteger,
-					Name:        "project-number",
-					Description: "Project number (overrides channel default)",
-					Required:    false,
+					Type:         discordgo.ApplicationCommandOptionInteger,
+					Name:         "project-number",
+					Description:  "Project number (overrides channel default)",
+					Required:     false,
+					Autocomplete: true,
 				},
 				{
-					Type:        discordgo.ApplicationCommandOptionString,
-					Name:        "repo",
-					Description: "Repository in format: owner/repo (overrides channel default)",
-					Required:    false,
+					Type:         discordgo.ApplicationCommandOptionString,
+					Name:         "repo",
+					Description:  "Repository in format: owner/repo (overrides channel default)",
+					Required:     false,
+					Autocomplete: true,
 				},
 				{
-					Type:        discordgo.ApplicationCommandOptionString,
-					Name:        "org",
-					Description: "Organization name (overrides channel default)",
-					Required:    false,
+					Type:         discordgo.ApplicationCommandOptionString,
+					Name:         "org",
+					Description:  "Organization name (overrides channel default)",
+					Required:     false,
+					Autocomplete: true,
 				},
 			},
 		},
 	}
+
+	for _, cmd := range b.commands {
+		if cmd.Contexts == nil {
+			cmd.Contexts = &allContexts
+			cmd.IntegrationTypes = &allInstalls
+		}
+	}
 }
 
 func (b *Bot) Start() error {
@@ -289,6 +568,8 @@ func (b *Bot) Start() error {
 		log.Printf("Registered command: %s", cmd.Name)
 	}
 
+	go b.runTokenHealthChecks()
+
 	return nil
 }
 
@@ -310,12 +591,38 @@ func (b *Bot) Stop() {
 }
 
 func (b *Bot) handleInteraction(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if i.Type == discordgo.InteractionMessageComponent {
+		b.handleComponent(s, i)
+		return
+	}
+
+	if i.Type == discordgo.InteractionApplicationCommandAutocomplete {
+		b.handleAutocomplete(s, i)
+		return
+	}
+
+	if i.Type == discordgo.InteractionModalSubmit {
+		b.handleModalSubmit(s, i)
+		return
+	}
+
 	if i.Type != discordgo.InteractionApplicationCommand {
 		return
 	}
 
 	data := i.ApplicationCommandData()
 
+	allowed, err := b.authorize(i)
+	if err != nil {
+		log.Printf("Failed to check permissions for %s: %v", data.Name, err)
+		b.respondError(s, i, "Failed to check permissions")
+		return
+	}
+	if !allowed {
+		b.respondError(s, i, "You don't have permission to use this command in this server")
+		return
+	}
+
 	switch data.Name {
 	case "gh-auth":
 		b.handleAuth(s, i)
@@ -325,8 +632,28 @@ func (b *Bot) handleInteraction(s *discordgo.Session, i *discordgo.InteractionCr
 		b.handleSetRepo(s, i)
 	case "gh-set-project":
 		b.handleSetProject(s, i)
+	case "gh-settings":
+		b.handleSettings(s, i)
+	case "gh-admin":
+		b.handleAdmin(s, i)
+	case "gh-audit":
+		b.handleAudit(s, i)
+	case "gh-subscribe":
+		b.handleSubscribe(s, i)
+	case "gh-unsubscribe":
+		b.handleUnsubscribe(s, i)
+	case "gh-subscriptions":
+		b.handleSubscriptions(s, i)
+	case "gh-forum-sync":
+		b.handleForumSync(s, i)
+	case "gh-unfurl":
+		b.handleUnfurl(s, i)
 	case "gh-issue-create":
 		b.handleIssueCreate(s, i)
+	case "gh-issue-new":
+		b.handleIssueNew(s, i)
+	case messageIssueCommand:
+		b.handleMessageIssue(s, i)
 	case "gh-issue-list":
 		b.handleIssueList(s, i)
 	case "gh-issue-view":
@@ -346,35 +673,82 @@ func (b *Bot) handleInteraction(s *discordgo.Session, i *discordgo.InteractionCr
 	}
 }
 
+// handleMessageCreate passes messages members post in servers to the features
+// that read chat.
+func (b *Bot) handleMessageCreate(s *discordgo.Session, m *discordgo.MessageCreate) {
+	if m.Author == nil || m.Author.Bot || m.WebhookID != "" || m.GuildID == "" {
+		return
+	}
+	if m.Type != discordgo.MessageTypeDefault && m.Type != discordgo.MessageTypeReply {
+		return
+	}
+
+	b.syncForumReply(s, m)
+	b.unfurlReferences(s, m)
+	b.expandPermalinks(s, m)
+}
+
+// handleComponent dispatches button and select menu interactions by CustomID prefix.
+func (b *Bot) handleComponent(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	customID := i.MessageComponentData().CustomID
+
+	switch {
+	case strings.HasPrefix(customID, listingCustomIDPrefix):
+		b.handleListingComponent(s, i)
+	default:
+		b.respondError(s, i, "Unknown component")
+	}
+}
+
 func (b *Bot) respondError(s *discordgo.Session, i *discordgo.InteractionCreate, message string) {
-	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseChannelMessageWithSource,
-		Data: &discordgo.InteractionResponseData{
-			Content: fmt.Sprintf("❌ Error: %s", message),
-			Flags:   discordgo.MessageFlagsEphemeral,
-		},
+	b.respond(s, i, &discordgo.InteractionResponseData{
+		Content: fmt.Sprintf("❌ Error: %s", message),
+		Flags:   discordgo.MessageFlagsEphemeral,
 	})
 }
 
 func (b *Bot) respondSuccess(s *discordgo.Session, i *discordgo.InteractionCreate, message string) {
-	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseChannelMessageWithSource,
-		Data: &discordgo.InteractionResponseData{
-			Content: message,
-		},
+	b.respond(s, i, &discordgo.InteractionResponseData{
+		Content: message,
 	})
 }
 
 func (b *Bot) respondEphemeral(s *discordgo.Session, i *discordgo.InteractionCreate, message string) {
-	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseChannelMessageWithSource,
-		Data: &discordgo.InteractionResponseData{
-			Content: message,
-			Flags:   discordgo.MessageFlagsEphemeral,
-		},
+	b.respond(s, i, &discordgo.InteractionResponseData{
+		Content: message,
+		Flags:   discordgo.MessageFlagsEphemeral,
 	})
 }
 
+// getInstallationClient returns a GitHub client that acts as the bot's GitHub App
+// installation in org, for actions that are not performed on behalf of a user.
+func (b *Bot) getInstallationClient(org string) (*github.Client, error) {
+	if b.githubApp == nil {
+		return nil, fmt.Errorf("GitHub App authentication is not configured")
+	}
+
+	return b.githubApp.Client(org)
+}
+
+// getInstallationToken returns an installation access token for org. Unlike
+// getInstallationClient it fails straight away when the App isn't installed there.
+func (b *Bot) getInstallationToken(org string) (string, error) {
+	if b.githubApp == nil {
+		return "", fmt.Errorf("GitHub App authentication is not configured")
+	}
+
+	return b.githubApp.Token(org)
+}
+
+// invokingUser returns the user who sent i. Member is only set for interactions
+// in a server; DMs and user-installed contexts only set User.
+func invokingUser(i *discordgo.InteractionCreate) *discordgo.User {
+	if i.Member != nil && i.Member.User != nil {
+		return i.Member.User
+	}
+	return i.User
+}
+
 func (b *Bot) getStringOption(options []*discordgo.ApplicationCommandInteractionDataOption, name string) string {
 	for _, opt := range options {
 		if opt.Name == name {
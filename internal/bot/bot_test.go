@@ -0,0 +1,43 @@
+package bot
+
+import (
+	"path/filepath"
+	"testing"
+
+	"discord-github-bot/internal/config"
+	"discord-github-bot/internal/database"
+	"discord-github-bot/internal/database/storetest"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// newTestBot returns a Bot backed by an empty SQLite database. Its session is
+// never opened; tests put the guilds and channels they need in its state.
+func newTestBot(t *testing.T) *Bot {
+	db, err := database.New(filepath.Join(t.TempDir(), "bot.db"), storetest.Keys)
+	if err != nil {
+		t.Fatalf("database.New: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	b, err := New(&config.Config{DiscordBotToken: "test", GitHubURL: "https://github.com"}, db, nil, nil)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	return b
+}
+
+// addChannels puts channels, and the guild they are in, in b's session state.
+func addChannels(t *testing.T, b *Bot, guildID string, channels ...*discordgo.Channel) {
+	if _, err := b.session.State.Guild(guildID); err != nil {
+		if err := b.session.State.GuildAdd(&discordgo.Guild{ID: guildID}); err != nil {
+			t.Fatalf("GuildAdd: %v", err)
+		}
+	}
+	for _, channel := range channels {
+		channel.GuildID = guildID
+		if err := b.session.State.ChannelAdd(channel); err != nil {
+			t.Fatalf("ChannelAdd: %v", err)
+		}
+	}
+}
@@ -0,0 +1,457 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"discord-github-bot/internal/database"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/google/go-github/v57/github"
+)
+
+const (
+	// syncMarkerPrefix tags issue comments mirrored from Discord, so their
+	// webhook deliveries aren't posted back into the thread.
+	syncMarkerPrefix = "<!-- discord-message:"
+
+	// maxSyncedCommentLength is Discord's limit on an embed description.
+	maxSyncedCommentLength = 4096
+
+	// archiveAuditWindow is how recent a thread archive audit log entry must be
+	// to count as the member closing the post rather than Discord auto-archiving it.
+	archiveAuditWindow = 30 * time.Second
+)
+
+func (b *Bot) handleForumSync(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if !guildInstalled(i) {
+		b.respondError(s, i, "This command can only be used in a server")
+		return
+	}
+
+	options := i.ApplicationCommandData().Options
+	enabled := true
+	forumID := ""
+	for _, opt := range options {
+		switch opt.Name {
+		case "enabled":
+			enabled = opt.BoolValue()
+		case "forum":
+			forumID = opt.ChannelValue(nil).ID
+		}
+	}
+
+	if forumID == "" {
+		forum, err := b.parentForum(s, i.ChannelID)
+		if err != nil {
+			log.Printf("Failed to look up channel %s: %v", i.ChannelID, err)
+		}
+		if forum == nil {
+			b.respondError(s, i, "Run this in a forum post or pass the `forum` option")
+			return
+		}
+		forumID = forum.ID
+	}
+
+	settings, err := b.db.GetChannelSettings(forumID)
+	if err == nil {
+		settings.ForumSync = enabled
+		err = b.db.SaveChannelSettings(settings)
+	}
+	b.audit(i, &database.AuditEntry{Details: fmt.Sprintf("forum sync for <#%s>: %t", forumID, enabled)}, err)
+	if err != nil {
+		log.Printf("Failed to save forum sync setting: %v", err)
+		b.respondError(s, i, "Failed to save channel settings")
+		return
+	}
+
+	if !enabled {
+		b.respondSuccess(s, i, fmt.Sprintf("✅ Issues created from posts in <#%s> are no longer linked. Existing links keep syncing.", forumID))
+		return
+	}
+
+	message := fmt.Sprintf("✅ Issues created from posts in <#%s> are now linked to the post: replies, GitHub comments and closing are mirrored between them.", forumID)
+	if !b.config.DiscordMessageContent {
+		message += "\n\n⚠️ The bot can't read message content, so replies won't be copied to GitHub until its operator enables the Message Content intent."
+	}
+	if b.config.GitHubWebhookSecret == "" || !b.config.OAuthServerEnabled {
+		message += "\n\n⚠️ Webhooks are not enabled on this bot, so GitHub comments and closes won't reach Discord."
+	}
+	if b.githubApp == nil {
+		message += "\n\nReplies are posted from each author's own GitHub account, so replies from members who haven't run `/gh-auth` won't be copied to GitHub."
+	}
+
+	b.respondSuccess(s, i, message)
+}
+
+// parentForum returns the forum channelID is a post in, or nil if it isn't one.
+func (b *Bot) parentForum(s *discordgo.Session, channelID string) (*discordgo.Channel, error) {
+	channel, err := b.lookupChannel(s, channelID)
+	if err != nil {
+		return nil, err
+	}
+	if !channel.IsThread() || channel.ParentID == "" {
+		return nil, nil
+	}
+
+	parent, err := b.lookupChannel(s, channel.ParentID)
+	if err != nil {
+		return nil, err
+	}
+	if parent.Type != discordgo.ChannelTypeGuildForum {
+		return nil, nil
+	}
+
+	return parent, nil
+}
+
+// linkForumPost links issue to the forum post i was sent in, if the forum has
+// sync turned on and the post isn't linked yet. Failures are only logged, as
+// the issue has already been created.
+func (b *Bot) linkForumPost(s *discordgo.Session, i *discordgo.InteractionCreate, repo string, issue *github.Issue) {
+	if !guildInstalled(i) {
+		return
+	}
+
+	forum, err := b.parentForum(s, i.ChannelID)
+	if err != nil {
+		log.Printf("Failed to look up channel %s: %v", i.ChannelID, err)
+		return
+	}
+	if forum == nil {
+		return
+	}
+
+	settings, err := b.db.GetChannelSettings(forum.ID)
+	if err != nil {
+		log.Printf("Failed to get channel settings: %v", err)
+		return
+	}
+	if !settings.ForumSync {
+		return
+	}
+
+	existing, err := b.db.GetIssueLinkByThread(i.ChannelID)
+	if err != nil {
+		log.Printf("Failed to get issue link: %v", err)
+		return
+	}
+	if existing != nil {
+		return
+	}
+
+	link := &database.IssueLink{
+		ThreadID:    i.ChannelID,
+		GuildID:     i.GuildID,
+		Repo:        repo,
+		IssueNumber: issue.GetNumber(),
+		CreatedBy:   invokingUser(i).ID,
+	}
+	if err := b.db.SaveIssueLink(link); err != nil {
+		log.Printf("Failed to link thread %s to %s#%d: %v", link.ThreadID, repo, link.IssueNumber, err)
+		return
+	}
+
+	content := fmt.Sprintf("🔗 This post is synced with GitHub issue **#%d** %s\n%s", issue.GetNumber(), issue.GetTitle(), issue.GetHTMLURL())
+	if _, err := s.ChannelMessageSend(link.ThreadID, content); err != nil {
+		log.Printf("Failed to announce link in thread %s: %v", link.ThreadID, err)
+	}
+}
+
+// Credentials a forum post's GitHub writes can be made with, as recorded in the audit log.
+const (
+	credentialMember       = "the member's GitHub account"
+	credentialInstallation = "the GitHub App installation"
+)
+
+// syncClient returns a client for commenting on link's issue on behalf of
+// discordID, and which credential it uses. If discordID hasn't linked GitHub
+// it falls back to the bot's GitHub App installation. It never uses the
+// account of the member who linked the post, who didn't agree to everyone in
+// the forum commenting as them.
+func (b *Bot) syncClient(link *database.IssueLink, discordID string) (client *github.Client, credential string, err error) {
+	if client, err := b.oauth.GetGitHubClient(discordID); err == nil {
+		return client, credentialMember, nil
+	}
+
+	client, err = b.repoInstallationClient(link.Repo)
+	if err != nil {
+		return nil, "", fmt.Errorf("author hasn't linked GitHub and the GitHub App isn't installed for %s: %w", link.Repo, err)
+	}
+	return client, credentialInstallation, nil
+}
+
+// repoInstallationClient returns a client acting as the bot's GitHub App
+// installation on repo's owner, or an error if the App isn't installed there.
+func (b *Bot) repoInstallationClient(repo string) (*github.Client, error) {
+	// The installation client fetches tokens lazily, so mint one first to find
+	// out whether the App is installed at all.
+	owner, _, _ := strings.Cut(repo, "/")
+	if _, err := b.getInstallationToken(owner); err != nil {
+		return nil, err
+	}
+	return b.getInstallationClient(owner)
+}
+
+// syncForumReply copies a reply in a linked forum post to the GitHub issue.
+func (b *Bot) syncForumReply(s *discordgo.Session, m *discordgo.MessageCreate) {
+	// Skip the database for channels the cache knows aren't threads.
+	if channel, err := s.State.Channel(m.ChannelID); err == nil && !channel.IsThread() {
+		return
+	}
+
+	link, err := b.db.GetIssueLinkByThread(m.ChannelID)
+	if err != nil {
+		log.Printf("Failed to get issue link for thread %s: %v", m.ChannelID, err)
+		return
+	}
+	if link == nil {
+		return
+	}
+
+	client, credential, err := b.syncClient(link, m.Author.ID)
+	if err != nil {
+		log.Printf("Not syncing message %s to %s#%d: %v", m.ID, link.Repo, link.IssueNumber, err)
+		return
+	}
+
+	body := syncedCommentBody(m.Message, link.GuildID, credential == credentialMember)
+	if body == "" {
+		return
+	}
+
+	owner, repoName, _ := strings.Cut(link.Repo, "/")
+	_, _, err = client.Issues.CreateComment(context.Background(), owner, repoName, link.IssueNumber, &github.IssueComment{Body: &body})
+	b.auditAs(link.GuildID, m.ChannelID, m.Author.ID, &database.AuditEntry{
+		Command:     "gh-forum-sync",
+		Repo:        link.Repo,
+		IssueNumber: link.IssueNumber,
+		Details:     "reply copied as a comment using " + credential,
+	}, err)
+	if err != nil {
+		log.Printf("Failed to sync message %s to %s#%d: %v", m.ID, link.Repo, link.IssueNumber, err)
+	}
+}
+
+// syncedCommentBody formats a Discord reply as an issue comment. Replies from
+// members who haven't linked GitHub are quoted and attributed to them.
+func syncedCommentBody(message *discordgo.Message, guildID string, asUser bool) string {
+	var body strings.Builder
+
+	content := strings.TrimSpace(message.Content)
+	for _, attachment := range message.Attachments {
+		content += fmt.Sprintf("\n[%s](%s)", attachment.Filename, attachment.URL)
+	}
+	content = strings.TrimSpace(content)
+	if content == "" {
+		return ""
+	}
+
+	jumpURL := fmt.Sprintf("https://discord.com/channels/%s/%s/%s", guildID, message.ChannelID, message.ID)
+	if asUser {
+		body.WriteString(content)
+		body.WriteString(fmt.Sprintf("\n\n_Sent from [Discord](%s)_", jumpURL))
+	} else {
+		body.WriteString(fmt.Sprintf("**%s** [wrote on Discord](%s):\n\n", message.Author.Username, jumpURL))
+		body.WriteString("> " + strings.ReplaceAll(content, "\n", "\n> "))
+	}
+
+	body.WriteString(fmt.Sprintf("\n\n%s%s -->", syncMarkerPrefix, message.ID))
+	return body.String()
+}
+
+// isSyncedComment reports whether an issue comment was copied from Discord by
+// syncForumReply, so mirroring it back would post the reply twice.
+func isSyncedComment(body string) bool {
+	return strings.Contains(body, syncMarkerPrefix)
+}
+
+// handleThreadUpdate closes the linked issue when a member closes a forum post.
+func (b *Bot) handleThreadUpdate(s *discordgo.Session, t *discordgo.ThreadUpdate) {
+	if t.ThreadMetadata == nil || !t.ThreadMetadata.Archived {
+		return
+	}
+	if t.BeforeUpdate != nil && t.BeforeUpdate.ThreadMetadata != nil && t.BeforeUpdate.ThreadMetadata.Archived {
+		return
+	}
+
+	link, err := b.db.GetIssueLinkByThread(t.ID)
+	if err != nil {
+		log.Printf("Failed to get issue link for thread %s: %v", t.ID, err)
+		return
+	}
+	if link == nil {
+		return
+	}
+
+	closedBy, err := b.threadArchivedBy(s, t.GuildID, t.ID)
+	if err != nil {
+		log.Printf("Failed to read audit log for thread %s: %v", t.ID, err)
+		return
+	}
+	// Posts archived by Discord for inactivity, or by the bot mirroring a
+	// GitHub close, leave the issue alone.
+	if closedBy == "" || closedBy == s.State.User.ID {
+		return
+	}
+
+	// Closing the post must not let members close issues /gh-issue-close wouldn't.
+	member, err := b.channelMember(s, t.GuildID, t.ParentID, closedBy)
+	if err != nil {
+		log.Printf("Failed to get permissions of %s in thread %s: %v", closedBy, t.ID, err)
+		return
+	}
+	allowed, err := b.memberAllowed(t.GuildID, member, actionClose)
+	if err != nil {
+		log.Printf("Failed to check permissions of %s in thread %s: %v", closedBy, t.ID, err)
+		return
+	}
+	if !allowed {
+		log.Printf("Not closing %s#%d: %s may not close issues", link.Repo, link.IssueNumber, closedBy)
+		return
+	}
+
+	// Unlike replies, closing never falls back to the member who linked the post,
+	// whose account might be able to close issues the closer can't.
+	credential := credentialMember
+	client, err := b.oauth.GetGitHubClient(closedBy)
+	if err != nil {
+		credential = credentialInstallation
+		client, err = b.repoInstallationClient(link.Repo)
+	}
+	if err != nil {
+		log.Printf("Not closing %s#%d: %s hasn't linked GitHub and the App isn't installed: %v", link.Repo, link.IssueNumber, closedBy, err)
+		return
+	}
+
+	owner, repoName, _ := strings.Cut(link.Repo, "/")
+	ctx := context.Background()
+	issue, _, err := client.Issues.Get(ctx, owner, repoName, link.IssueNumber)
+	if err != nil {
+		log.Printf("Failed to get %s#%d: %v", link.Repo, link.IssueNumber, err)
+		return
+	}
+	if issue.GetState() == "closed" {
+		return
+	}
+
+	state := "closed"
+	_, _, err = client.Issues.Edit(ctx, owner, repoName, link.IssueNumber, &github.IssueRequest{State: &state})
+	b.auditAs(t.GuildID, t.ID, closedBy, &database.AuditEntry{
+		Command:     "gh-forum-sync",
+		Repo:        link.Repo,
+		IssueNumber: link.IssueNumber,
+		Details:     "issue closed from the forum post using " + credential,
+	}, err)
+	if err != nil {
+		log.Printf("Failed to close %s#%d for thread %s: %v", link.Repo, link.IssueNumber, t.ID, err)
+		return
+	}
+
+	log.Printf("Closed %s#%d after thread %s was closed by %s", link.Repo, link.IssueNumber, t.ID, closedBy)
+}
+
+// threadArchivedBy returns the ID of the member who just archived threadID, or
+// "" if the audit log has no such entry, as when Discord archives an inactive
+// thread. The bot needs the View Audit Log permission.
+func (b *Bot) threadArchivedBy(s *discordgo.Session, guildID, threadID string) (string, error) {
+	auditLog, err := s.GuildAuditLog(guildID, "", "", int(discordgo.AuditLogActionThreadUpdate), 10)
+	if err != nil {
+		return "", err
+	}
+
+	return archivingUser(auditLog.AuditLogEntries, threadID, time.Now()), nil
+}
+
+// archivingUser returns who archived threadID according to the thread update
+// audit log entries, ignoring entries older than archiveAuditWindow at now.
+func archivingUser(entries []*discordgo.AuditLogEntry, threadID string, now time.Time) string {
+	for _, entry := range entries {
+		if entry.TargetID != threadID {
+			continue
+		}
+
+		createdAt, err := discordgo.SnowflakeTimestamp(entry.ID)
+		if err != nil || now.Sub(createdAt) > archiveAuditWindow {
+			continue
+		}
+
+		for _, change := range entry.Changes {
+			if change.Key != nil && *change.Key == discordgo.AuditLogChangeKeyArchived && change.NewValue == true {
+				return entry.UserID
+			}
+		}
+	}
+
+	return ""
+}
+
+// handleThreadDelete forgets links to deleted forum posts.
+func (b *Bot) handleThreadDelete(s *discordgo.Session, t *discordgo.ThreadDelete) {
+	if err := b.db.DeleteIssueLink(t.ID); err != nil {
+		log.Printf("Failed to delete issue link for thread %s: %v", t.ID, err)
+	}
+}
+
+// syncIssueEvent mirrors GitHub comments and closes on linked issues into
+// their forum posts.
+func (b *Bot) syncIssueEvent(event interface{}) error {
+	switch e := event.(type) {
+	case *github.IssueCommentEvent:
+		if e.GetAction() != "created" {
+			return nil
+		}
+		if isSyncedComment(e.GetComment().GetBody()) {
+			return nil
+		}
+
+		link, err := b.db.GetIssueLinkByIssue(e.GetRepo().GetFullName(), e.GetIssue().GetNumber())
+		if err != nil || link == nil {
+			return err
+		}
+
+		comment := e.GetComment()
+		embed := eventEmbed(comment.GetUser(), colorNeutral)
+		embed.Title = "New comment on GitHub"
+		embed.URL = comment.GetHTMLURL()
+		embed.Description = truncate(comment.GetBody(), maxSyncedCommentLength)
+
+		_, err = b.session.ChannelMessageSendEmbed(link.ThreadID, embed)
+		return err
+	case *github.IssuesEvent:
+		if e.GetAction() != "closed" {
+			return nil
+		}
+
+		link, err := b.db.GetIssueLinkByIssue(e.GetRepo().GetFullName(), e.GetIssue().GetNumber())
+		if err != nil || link == nil {
+			return err
+		}
+
+		// Posts closed in Discord are already archived; posting would reopen them.
+		thread, err := b.lookupChannel(b.session, link.ThreadID)
+		if err != nil {
+			return err
+		}
+		if thread.ThreadMetadata != nil && thread.ThreadMetadata.Archived {
+			return nil
+		}
+
+		content := fmt.Sprintf("🔒 Issue closed on GitHub by **%s**", e.GetSender().GetLogin())
+		if reason := e.GetIssue().GetStateReason(); reason != "" {
+			content += fmt.Sprintf(" as %s", strings.ReplaceAll(reason, "_", " "))
+		}
+		if _, err := b.session.ChannelMessageSend(link.ThreadID, content); err != nil {
+			log.Printf("Failed to post close notice in thread %s: %v", link.ThreadID, err)
+		}
+
+		archived := true
+		_, err = b.session.ChannelEditComplex(link.ThreadID, &discordgo.ChannelEdit{Archived: &archived})
+		return err
+	}
+
+	return nil
+}
@@ -0,0 +1,151 @@
+package bot
+
+import (
+	"strconv"
+	"testing"
+	"time"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestSyncedCommentBody(t *testing.T) {
+	author := &discordgo.User{Username: "alice"}
+	jumpURL := "https://discord.com/channels/g1/t1/m1"
+
+	tests := []struct {
+		name        string
+		content     string
+		attachments []*discordgo.MessageAttachment
+		asUser      bool
+		want        string
+	}{
+		{
+			name:    "as user",
+			content: "Still broken on 1.2",
+			asUser:  true,
+			want:    "Still broken on 1.2\n\n_Sent from [Discord](" + jumpURL + ")_\n\n<!-- discord-message:m1 -->",
+		},
+		{
+			name:    "quoted",
+			content: "Still broken\non 1.2",
+			want:    "**alice** [wrote on Discord](" + jumpURL + "):\n\n> Still broken\n> on 1.2\n\n<!-- discord-message:m1 -->",
+		},
+		{
+			name:        "attachments only",
+			attachments: []*discordgo.MessageAttachment{{Filename: "trace.txt", URL: "https://cdn.example/trace.txt"}},
+			asUser:      true,
+			want:        "[trace.txt](https://cdn.example/trace.txt)\n\n_Sent from [Discord](" + jumpURL + ")_\n\n<!-- discord-message:m1 -->",
+		},
+		{
+			name:    "whitespace only",
+			content: " \n ",
+			want:    "",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message := &discordgo.Message{
+				ID:          "m1",
+				ChannelID:   "t1",
+				Content:     tt.content,
+				Author:      author,
+				Attachments: tt.attachments,
+			}
+
+			got := syncedCommentBody(message, "g1", tt.asUser)
+			if got != tt.want {
+				t.Errorf("syncedCommentBody() = %q, want %q", got, tt.want)
+			}
+			if got != "" && !isSyncedComment(got) {
+				t.Errorf("isSyncedComment(%q) = false, want true so it isn't mirrored back", got)
+			}
+		})
+	}
+}
+
+func TestIsSyncedComment(t *testing.T) {
+	tests := []struct {
+		body string
+		want bool
+	}{
+		{"Looks good to me", false},
+		{"See <!-- a regular HTML comment -->", false},
+		{"**alice** wrote on Discord:\n\n> hi\n\n<!-- discord-message:123 -->", true},
+	}
+
+	for _, tt := range tests {
+		if got := isSyncedComment(tt.body); got != tt.want {
+			t.Errorf("isSyncedComment(%q) = %v, want %v", tt.body, got, tt.want)
+		}
+	}
+}
+
+// snowflakeAt returns a Discord ID created at t.
+func snowflakeAt(t time.Time) string {
+	const discordEpoch = 1420070400000
+	return strconv.FormatInt((t.UnixMilli()-discordEpoch)<<22, 10)
+}
+
+func TestArchivingUser(t *testing.T) {
+	now := time.Now()
+	archivedKey := discordgo.AuditLogChangeKeyArchived
+	nameKey := discordgo.AuditLogChangeKeyName
+
+	entry := func(id, threadID, userID string, key *discordgo.AuditLogChangeKey, newValue interface{}) *discordgo.AuditLogEntry {
+		return &discordgo.AuditLogEntry{
+			ID:       id,
+			TargetID: threadID,
+			UserID:   userID,
+			Changes:  []*discordgo.AuditLogChange{{Key: key, NewValue: newValue}},
+		}
+	}
+
+	tests := []struct {
+		name    string
+		entries []*discordgo.AuditLogEntry
+		want    string
+	}{
+		{
+			name:    "member archived the thread",
+			entries: []*discordgo.AuditLogEntry{entry(snowflakeAt(now.Add(-5*time.Second)), "t1", "u1", &archivedKey, true)},
+			want:    "u1",
+		},
+		{
+			name:    "no entry, as when Discord archives an inactive thread",
+			entries: nil,
+			want:    "",
+		},
+		{
+			name:    "entry older than the window",
+			entries: []*discordgo.AuditLogEntry{entry(snowflakeAt(now.Add(-archiveAuditWindow-time.Second)), "t1", "u1", &archivedKey, true)},
+			want:    "",
+		},
+		{
+			name:    "another thread",
+			entries: []*discordgo.AuditLogEntry{entry(snowflakeAt(now), "t2", "u1", &archivedKey, true)},
+			want:    "",
+		},
+		{
+			name:    "unarchived",
+			entries: []*discordgo.AuditLogEntry{entry(snowflakeAt(now), "t1", "u1", &archivedKey, false)},
+			want:    "",
+		},
+		{
+			name: "renamed, then archived by someone else",
+			entries: []*discordgo.AuditLogEntry{
+				entry(snowflakeAt(now), "t1", "u1", &nameKey, "new name"),
+				entry(snowflakeAt(now.Add(-time.Second)), "t1", "u2", &archivedKey, true),
+			},
+			want: "u2",
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := archivingUser(tt.entries, "t1", now); got != tt.want {
+				t.Errorf("archivingUser() = %q, want %q", got, tt.want)
+			}
+		})
+	}
+}
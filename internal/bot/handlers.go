@@ -4,15 +4,20 @@ import (
 	"context"
 	"fmt"
 	"log"
+	"net/url"
 	"strconv"
 	"strings"
 
+	"discord-github-bot/internal/database"
+
 	"github.com/bwmarrin/discordgo"
 	"github.com/google/go-github/v57/github"
 )
 
 func (b *Bot) handleAuth(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	userID := i.Member.User.ID
+	b.deferResponse(s, i, true)
+
+	userID := invokingUser(i).ID
 
 	// Check if the user is already authenticated
 	user, err := b.db.GetUser(userID)
@@ -22,7 +27,8 @@ func (b *Bot) handleAuth(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		return
 	}
 
-	if user != nil {
+	// Stale users were flagged by the token health check and may link again directly.
+	if user != nil && !user.TokenStale {
 		// User is already authenticated
 		b.respondEphemeral(s, i, fmt.Sprintf(
 			"You are already authenticated as **%s**. If you wish to authenticate with a different GitHub account, please use the `/gh-unauth` command first, then try `/gh-auth` again. You may also need to clear your browser's GitHub cookies or use an incognito/private browsing window.",
@@ -31,17 +37,90 @@ func (b *Bot) handleAuth(s *discordgo.Session, i *discordgo.InteractionCreate) {
 		return
 	}
 
+	method := b.getStringOption(i.ApplicationCommandData().Options, "method")
+	if method == "" {
+		method = "browser"
+		if !b.config.OAuthServerEnabled {
+			method = "device"
+		}
+	}
+
+	if method == "device" {
+		b.startDeviceAuth(s, i, userID)
+		return
+	}
+
+	if !b.config.OAuthServerEnabled {
+		b.respondError(s, i, "Browser sign-in is disabled on this bot. Use `/gh-auth method:device` instead.")
+		return
+	}
+
 	// User is not authenticated, proceed with original flow
-	authURL := fmt.Sprintf("%s/auth?discord_id=%s", b.config.PublicURL, userID)
+	authURL, err := b.oauth.GenerateAuthLink(userID)
+	if err != nil {
+		log.Printf("Failed to generate auth link: %v", err)
+		b.respondError(s, i, "Failed to generate authentication link")
+		return
+	}
 
 	b.respondEphemeral(s, i, fmt.Sprintf(
-		"Click the link below to authenticate with **one** GitHub account:\n%s\n\nThis link will expire in 10 minutes. If you wish to switch accounts later, use `/gh-unauth` first.",
+		"Click the link below to authenticate with **one** GitHub account:\n%s\n\nThis link can only be used once and will expire in 10 minutes. If you wish to switch accounts later, use `/gh-unauth` first.",
 		authURL,
 	))
 }
 
+// startDeviceAuth replies with a device flow user code and edits the reply
+// once GitHub reports that the user approved or the code expired.
+func (b *Bot) startDeviceAuth(s *discordgo.Session, i *discordgo.InteractionCreate, userID string) {
+	deviceAuth, err := b.oauth.StartDeviceLogin(userID, func(user *database.User, err error) {
+		var content string
+		if err != nil {
+			log.Printf("Device flow failed for %s: %v", userID, err)
+			content = "❌ GitHub authentication did not complete. Run `/gh-auth` again to get a new code."
+		} else {
+			content = fmt.Sprintf("✅ Authenticated as **%s**.", user.GitHubUsername)
+		}
+
+		if _, err := s.InteractionResponseEdit(i.Interaction, &discordgo.WebhookEdit{Content: &content}); err != nil {
+			log.Printf("Failed to update device flow message: %v", err)
+		}
+	})
+	if err != nil {
+		log.Printf("Failed to start device flow: %v", err)
+		b.respondError(s, i, "Failed to start GitHub device authentication")
+		return
+	}
+
+	b.respondEphemeral(s, i, fmt.Sprintf(
+		"Open %s and enter the code **%s** to authenticate with **one** GitHub account.\n\nThe code expires <t:%d:R>. This message will update once you approve.",
+		deviceAuth.VerificationURI,
+		deviceAuth.UserCode,
+		deviceAuth.Expiry.Unix(),
+	))
+}
+
 func (b *Bot) handleUnauth(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	userID := i.Member.User.ID
+	b.deferResponse(s, i, true)
+
+	userID := invokingUser(i).ID
+
+	user, err := b.db.GetUser(userID)
+	if err != nil {
+		log.Printf("Failed to get user from DB: %v", err)
+		b.respondError(s, i, "An error occurred while checking authentication status.")
+		return
+	}
+
+	if user == nil {
+		b.respondEphemeral(s, i, "You are not authenticated with GitHub.")
+		return
+	}
+
+	// Revoke on GitHub first, but still unlink locally if GitHub can't be reached.
+	revokeErr := b.oauth.RevokeGrant(userID)
+	if revokeErr != nil {
+		log.Printf("Failed to revoke GitHub grant: %v", revokeErr)
+	}
 
 	if err := b.db.DeleteUser(userID); err != nil {
 		log.Printf("Failed to delete user: %v", err)
@@ -49,83 +128,79 @@ func (b *Bot) handleUnauth(s *discordgo.Session, i *discordgo.InteractionCreate)
 		return
 	}
 
-	b.respondEphemeral(s, i, "Your GitHub authentication has been removed.")
+	if revokeErr != nil {
+		b.respondEphemeral(s, i, fmt.Sprintf(
+			"Your GitHub authentication has been removed from this bot, but revoking access on GitHub failed. You can revoke it manually at %s/settings/applications.",
+			b.config.GitHubURL,
+		))
+		return
+	}
+
+	b.respondEphemeral(s, i, "Your GitHub authentication has been removed and access has been revoked on GitHub.")
 }
 
 func (b *Bot) handleSetRepo(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	repo := b.getStringOption(i.ApplicationCommandData().Options, "repo")
-	channelID := i.ChannelID
+	scope := b.getStringOption(i.ApplicationCommandData().Options, "scope")
 
 	if !strings.Contains(repo, "/") {
 		b.respondError(s, i, "Repository must be in format: owner/repo")
 		return
 	}
 
-	settings, err := b.db.GetChannelSettings(channelID)
+	target, err := b.saveDefaults(s, i, scope, func(defaultRepo, _ *string) {
+		*defaultRepo = repo
+	})
+	b.audit(i, &database.AuditEntry{Repo: repo, Details: "default for " + target}, err)
 	if err != nil {
-		log.Printf("Failed to get channel settings: %v", err)
-		b.respondError(s, i, "Failed to get channel settings")
-		return
-	}
-
-	settings.DefaultRepo = repo
-
-	if err := b.db.SaveChannelSettings(settings); err != nil {
-		log.Printf("Failed to save channel settings: %v", err)
-		b.respondError(s, i, "Failed to save channel settings")
+		b.respondError(s, i, err.Error())
 		return
 	}
 
-	b.respondSuccess(s, i, fmt.Sprintf("✅ Default repository set to: %s", repo))
+	b.respondSuccess(s, i, fmt.Sprintf("✅ Default repository for %s set to: %s", target, repo))
 }
 
 func (b *Bot) handleSetProject(s *discordgo.Session, i *discordgo.InteractionCreate) {
 	project := b.getStringOption(i.ApplicationCommandData().Options, "project")
-	channelID := i.ChannelID
+	scope := b.getStringOption(i.ApplicationCommandData().Options, "scope")
 
 	// Parse GitHub project URL if provided
-	// Format: https://github.com/orgs/{org}/projects/{number}
+	// Format: https://github.com/orgs/{org}/projects/{number} (or the GitHub Enterprise equivalent)
+	projectURLPrefix := b.config.GitHubURL + "/orgs/"
 	var projectValue string
-	if after, found := strings.CutPrefix(project, "https://github.com/orgs/"); found {
+	if after, found := strings.CutPrefix(project, projectURLPrefix); found {
 		parts := strings.Split(after, "/")
 		if len(parts) >= 3 && parts[1] == "projects" {
 			org := parts[0]
 			projectNumber := parts[2]
 			projectValue = fmt.Sprintf("%s/%s", org, projectNumber)
 		} else {
-			b.respondError(s, i, "Invalid GitHub project URL format. Expected: https://github.com/orgs/{org}/projects/{number}")
+			b.respondError(s, i, fmt.Sprintf("Invalid GitHub project URL format. Expected: %s{org}/projects/{number}", projectURLPrefix))
 			return
 		}
 	} else {
 		projectValue = project
 	}
 
-	settings, err := b.db.GetChannelSettings(channelID)
+	target, err := b.saveDefaults(s, i, scope, func(_, defaultProject *string) {
+		*defaultProject = projectValue
+	})
+	b.audit(i, &database.AuditEntry{Project: projectValue, Details: "default for " + target}, err)
 	if err != nil {
-		log.Printf("Failed to get channel settings: %v", err)
-		b.respondError(s, i, "Failed to get channel settings")
+		b.respondError(s, i, err.Error())
 		return
 	}
 
-	settings.DefaultProject = projectValue
-
-	if err := b.db.SaveChannelSettings(settings); err != nil {
-		log.Printf("Failed to save channel settings: %v", err)
-		b.respondError(s, i, "Failed to save channel settings")
-		return
-	}
-
-	b.respondSuccess(s, i, fmt.Sprintf("✅ Default project set to: %s", projectValue))
+	b.respondSuccess(s, i, fmt.Sprintf("✅ Default project for %s set to: %s", target, projectValue))
 }
 
 func (b *Bot) handleIssueCreate(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	userID := i.Member.User.ID
 	title := b.getStringOption(i.ApplicationCommandData().Options, "title")
 	body := b.getStringOption(i.ApplicationCommandData().Options, "body")
 	repo := b.getStringOption(i.ApplicationCommandData().Options, "repo")
 
 	if repo == "" {
-		settings, err := b.db.GetChannelSettings(i.ChannelID)
+		settings, err := b.getEffectiveSettings(s, i)
 		if err != nil || settings.DefaultRepo == "" {
 			b.respondError(s, i, "No repository specified and no default repository set for this channel")
 			return
@@ -133,32 +208,57 @@ func (b *Bot) handleIssueCreate(s *discordgo.Session, i *discordgo.InteractionCr
 		repo = settings.DefaultRepo
 	}
 
+	// Without a body, collect one in a modal so it can span multiple lines.
+	if body == "" {
+		b.openIssueModal(s, i, repo, title)
+		return
+	}
+
+	b.deferResponse(s, i, false)
+
+	createdIssue := b.createIssue(s, i, "gh-issue-create", repo, &github.IssueRequest{
+		Title: &title,
+		Body:  &body,
+	})
+	if createdIssue != nil {
+		b.respondIssueCreated(s, i, createdIssue)
+	}
+}
+
+// createIssue creates issue in repo as the invoking user and audits it under
+// command, the command the member started from. On failure it responds with
+// the error and returns nil. The interaction should already be deferred.
+func (b *Bot) createIssue(s *discordgo.Session, i *discordgo.InteractionCreate, command, repo string, issue *github.IssueRequest) *github.Issue {
+	userID := invokingUser(i).ID
+
 	parts := strings.Split(repo, "/")
 	if len(parts) != 2 {
 		b.respondError(s, i, "Invalid repository format. Use: owner/repo")
-		return
+		return nil
 	}
 	owner, repoName := parts[0], parts[1]
 
 	client, err := b.oauth.GetGitHubClient(userID)
 	if err != nil {
 		b.respondError(s, i, "You must authenticate first. Use /gh-auth")
-		return
+		return nil
 	}
 
 	ctx := context.Background()
-	issue := &github.IssueRequest{
-		Title: &title,
-		Body:  &body,
-	}
-
 	createdIssue, _, err := client.Issues.Create(ctx, owner, repoName, issue)
+	b.audit(i, &database.AuditEntry{Command: command, Repo: repo, IssueNumber: createdIssue.GetNumber()}, err)
 	if err != nil {
 		log.Printf("Failed to create issue: %v", err)
 		b.respondError(s, i, fmt.Sprintf("Failed to create issue: %v", err))
-		return
+		return nil
 	}
 
+	b.linkForumPost(s, i, repo, createdIssue)
+
+	return createdIssue
+}
+
+func (b *Bot) respondIssueCreated(s *discordgo.Session, i *discordgo.InteractionCreate, createdIssue *github.Issue) {
 	b.respondSuccess(s, i, fmt.Sprintf(
 		"✅ Issue created successfully!\n**#%d** %s\n%s",
 		createdIssue.GetNumber(),
@@ -168,7 +268,9 @@ func (b *Bot) handleIssueCreate(s *discordgo.Session, i *discordgo.InteractionCr
 }
 
 func (b *Bot) handleIssueList(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	userID := i.Member.User.ID
+	b.deferResponse(s, i, false)
+
+	userID := invokingUser(i).ID
 	repo := b.getStringOption(i.ApplicationCommandData().Options, "repo")
 	state := b.getStringOption(i.ApplicationCommandData().Options, "state")
 	query := b.getStringOption(i.ApplicationCommandData().Options, "query")
@@ -178,7 +280,7 @@ func (b *Bot) handleIssueList(s *discordgo.Session, i *discordgo.InteractionCrea
 	}
 
 	if repo == "" {
-		settings, err := b.db.GetChannelSettings(i.ChannelID)
+		settings, err := b.getEffectiveSettings(s, i)
 		if err != nil || settings.DefaultRepo == "" {
 			b.respondError(s, i, "No repository specified and no default repository set for this channel")
 			return
@@ -199,67 +301,63 @@ func (b *Bot) handleIssueList(s *discordgo.Session, i *discordgo.InteractionCrea
 		return
 	}
 
+	b.showListing(s, i, func(page url.Values) (*listingPage, error) {
+		return fetchIssuePage(client, owner, repoName, state, query, page)
+	})
+}
+
+// fetchIssuePage loads one page of /gh-issue-list results.
+func fetchIssuePage(client *github.Client, owner, repoName, state, query string, page url.Values) (*listingPage, error) {
 	ctx := context.Background()
+	repo := owner + "/" + repoName
+	listOpts := github.ListOptions{PerPage: listingPageSize, Page: listPage(page)}
+
+	var issues []*github.Issue
+	var resp *github.Response
+	var header string
 
 	// Use search API if query is provided for better filtering
 	if query != "" {
 		searchQuery := fmt.Sprintf("repo:%s/%s state:%s %s", owner, repoName, state, query)
 		searchOpts := &github.SearchOptions{
-			ListOptions: github.ListOptions{PerPage: 10},
+			ListOptions: listOpts,
 		}
 
-		result, _, err := client.Search.Issues(ctx, searchQuery, searchOpts)
+		result, searchResp, err := client.Search.Issues(ctx, searchQuery, searchOpts)
 		if err != nil {
 			log.Printf("Failed to search issues: %v", err)
-			b.respondError(s, i, fmt.Sprintf("Failed to search issues: %v", err))
-			return
+			return nil, fmt.Errorf("Failed to search issues: %v", err)
 		}
 
 		if result.GetTotal() == 0 {
-			b.respondSuccess(s, i, fmt.Sprintf("No issues found in %s matching query: %s", repo, query))
-			return
+			return &listingPage{content: fmt.Sprintf("No issues found in %s matching query: %s", repo, query), empty: true}, nil
 		}
 
-		var response strings.Builder
-		response.WriteString(fmt.Sprintf("**Issues in %s (state:%s, query:%s):**\n\n", repo, state, query))
-
-		for _, issue := range result.Issues {
-			issueState := "Open"
-			if issue.GetState() == "closed" {
-				issueState = "Closed"
-			}
-			response.WriteString(fmt.Sprintf("**[Issue #%d](%s)** %s (Status: %s)\n",
-				issue.GetNumber(),
-				issue.GetHTMLURL(),
-				issue.GetTitle(),
-				issueState,
-			))
+		issues, resp = result.Issues, searchResp
+		header = fmt.Sprintf("**Issues in %s (state:%s, query:%s):**\n\n", repo, state, query)
+	} else {
+		// Use regular list API for simple state filtering
+		opts := &github.IssueListByRepoOptions{
+			State:       state,
+			ListOptions: listOpts,
 		}
 
-		b.respondSuccess(s, i, response.String())
-		return
-	}
-
-	// Use regular list API for simple state filtering
-	opts := &github.IssueListByRepoOptions{
-		State:       state,
-		ListOptions: github.ListOptions{PerPage: 10},
-	}
+		listed, listResp, err := client.Issues.ListByRepo(ctx, owner, repoName, opts)
+		if err != nil {
+			log.Printf("Failed to list issues: %v", err)
+			return nil, fmt.Errorf("Failed to list issues: %v", err)
+		}
 
-	issues, _, err := client.Issues.ListByRepo(ctx, owner, repoName, opts)
-	if err != nil {
-		log.Printf("Failed to list issues: %v", err)
-		b.respondError(s, i, fmt.Sprintf("Failed to list issues: %v", err))
-		return
-	}
+		if len(listed) == 0 && listOpts.Page <= 1 {
+			return &listingPage{content: fmt.Sprintf("No %s issues found in %s", state, repo), empty: true}, nil
+		}
 
-	if len(issues) == 0 {
-		b.respondSuccess(s, i, fmt.Sprintf("No %s issues found in %s", state, repo))
-		return
+		issues, resp = listed, listResp
+		header = fmt.Sprintf("**Issues in %s (state:%s):**\n\n", repo, state)
 	}
 
 	var response strings.Builder
-	response.WriteString(fmt.Sprintf("**Issues in %s (state:%s):**\n\n", repo, state))
+	response.WriteString(header)
 
 	for _, issue := range issues {
 		issueState := "Open"
@@ -274,16 +372,18 @@ func (b *Bot) handleIssueList(s *discordgo.Session, i *discordgo.InteractionCrea
 		))
 	}
 
-	b.respondSuccess(s, i, response.String())
+	return &listingPage{content: response.String(), links: githubPageLinks(resp)}, nil
 }
 
 func (b *Bot) handleIssueView(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	userID := i.Member.User.ID
+	b.deferResponse(s, i, false)
+
+	userID := invokingUser(i).ID
 	number := b.getIntOption(i.ApplicationCommandData().Options, "number")
 	repo := b.getStringOption(i.ApplicationCommandData().Options, "repo")
 
 	if repo == "" {
-		settings, err := b.db.GetChannelSettings(i.ChannelID)
+		settings, err := b.getEffectiveSettings(s, i)
 		if err != nil || settings.DefaultRepo == "" {
 			b.respondError(s, i, "No repository specified and no default repository set for this channel")
 			return
@@ -339,22 +439,21 @@ func (b *Bot) handleIssueView(s *discordgo.Session, i *discordgo.InteractionCrea
 		},
 	}
 
-	s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
-		Type: discordgo.InteractionResponseChannelMessageWithSource,
-		Data: &discordgo.InteractionResponseData{
-			Embeds: []*discordgo.MessageEmbed{embed},
-		},
+	b.respond(s, i, &discordgo.InteractionResponseData{
+		Embeds: []*discordgo.MessageEmbed{embed},
 	})
 }
 
 func (b *Bot) handleIssueClose(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	userID := i.Member.User.ID
+	b.deferResponse(s, i, false)
+
+	userID := invokingUser(i).ID
 	number := b.getIntOption(i.ApplicationCommandData().Options, "number")
 	stateReason := b.getStringOption(i.ApplicationCommandData().Options, "state_reason")
 	repo := b.getStringOption(i.ApplicationCommandData().Options, "repo")
 
 	if repo == "" {
-		settings, err := b.db.GetChannelSettings(i.ChannelID)
+		settings, err := b.getEffectiveSettings(s, i)
 		if err != nil || settings.DefaultRepo == "" {
 			b.respondError(s, i, "No repository specified and no default repository set for this channel")
 			return
@@ -383,6 +482,7 @@ func (b *Bot) handleIssueClose(s *discordgo.Session, i *discordgo.InteractionCre
 	}
 
 	closedIssue, _, err := client.Issues.Edit(ctx, owner, repoName, number, issueRequest)
+	b.audit(i, &database.AuditEntry{Repo: repo, IssueNumber: number, Details: "reason: " + stateReason}, err)
 	if err != nil {
 		log.Printf("Failed to close issue: %v", err)
 		b.respondError(s, i, fmt.Sprintf("Failed to close issue: %v", err))
@@ -398,13 +498,15 @@ func (b *Bot) handleIssueClose(s *discordgo.Session, i *discordgo.InteractionCre
 }
 
 func (b *Bot) handleIssueComment(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	userID := i.Member.User.ID
+	b.deferResponse(s, i, false)
+
+	userID := invokingUser(i).ID
 	number := b.getIntOption(i.ApplicationCommandData().Options, "number")
 	comment := b.getStringOption(i.ApplicationCommandData().Options, "comment")
 	repo := b.getStringOption(i.ApplicationCommandData().Options, "repo")
 
 	if repo == "" {
-		settings, err := b.db.GetChannelSettings(i.ChannelID)
+		settings, err := b.getEffectiveSettings(s, i)
 		if err != nil || settings.DefaultRepo == "" {
 			b.respondError(s, i, "No repository specified and no default repository set for this channel")
 			return
@@ -431,6 +533,7 @@ func (b *Bot) handleIssueComment(s *discordgo.Session, i *discordgo.InteractionC
 	}
 
 	createdComment, _, err := client.Issues.CreateComment(ctx, owner, repoName, number, issueComment)
+	b.audit(i, &database.AuditEntry{Repo: repo, IssueNumber: number}, err)
 	if err != nil {
 		log.Printf("Failed to create comment: %v", err)
 		b.respondError(s, i, fmt.Sprintf("Failed to create comment: %v", err))
@@ -445,7 +548,9 @@ func (b *Bot) handleIssueComment(s *discordgo.Session, i *discordgo.InteractionC
 }
 
 func (b *Bot) handleProjectItemsList(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	userID := i.Member.User.ID
+	b.deferResponse(s, i, false)
+
+	userID := invokingUser(i).ID
 	projectNumber := b.getIntOption(i.ApplicationCommandData().Options, "project-number")
 	org := b.getStringOption(i.ApplicationCommandData().Options, "org")
 	query := b.getStringOption(i.ApplicationCommandData().Options, "query")
@@ -456,7 +561,7 @@ func (b *Bot) handleProjectItemsList(s *discordgo.Session, i *discordgo.Interact
 	}
 
 	if projectNumber == 0 || org == "" {
-		settings, err := b.db.GetChannelSettings(i.ChannelID)
+		settings, err := b.getEffectiveSettings(s, i)
 		if err != nil || settings.DefaultProject == "" {
 			b.respondError(s, i, "No project specified and no default project set for this channel")
 			return
@@ -483,16 +588,21 @@ func (b *Bot) handleProjectItemsList(s *discordgo.Session, i *discordgo.Interact
 		return
 	}
 
-	projectItemsResponse, err := b.githubREST.ListProjectItems(org, projectNumber, accessToken, 10, query)
+	b.showListing(s, i, func(page url.Values) (*listingPage, error) {
+		return b.fetchProjectItemsPage(org, projectNumber, accessToken, query, page)
+	})
+}
+
+// fetchProjectItemsPage loads one page of /gh-project-items-list results.
+func (b *Bot) fetchProjectItemsPage(org string, projectNumber int, accessToken, query string, page url.Values) (*listingPage, error) {
+	projectItemsResponse, links, err := b.githubREST.ListProjectItems(org, projectNumber, accessToken, listingPageSize, query, page)
 	if err != nil {
 		log.Printf("Failed to list project items using REST: %v", err)
-		b.respondError(s, i, fmt.Sprintf("Failed to list project items: %v", err))
-		return
+		return nil, fmt.Errorf("Failed to list project items: %v", err)
 	}
 
-	if len(*projectItemsResponse) == 0 {
-		b.respondSuccess(s, i, fmt.Sprintf("No items found in project #%d for organization %s with query: %s", projectNumber, org, query))
-		return
+	if len(*projectItemsResponse) == 0 && page == nil {
+		return &listingPage{content: fmt.Sprintf("No items found in project #%d for organization %s with query: %s", projectNumber, org, query), empty: true}, nil
 	}
 
 	var response strings.Builder
@@ -550,11 +660,13 @@ func (b *Bot) handleProjectItemsList(s *discordgo.Session, i *discordgo.Interact
 		response.WriteString(line)
 	}
 
-	b.respondSuccess(s, i, response.String())
+	return &listingPage{content: response.String(), links: links}, nil
 }
 
 func (b *Bot) handleProjectList(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	userID := i.Member.User.ID
+	b.deferResponse(s, i, false)
+
+	userID := invokingUser(i).ID
 	org := b.getStringOption(i.ApplicationCommandData().Options, "org")
 	query := b.getStringOption(i.ApplicationCommandData().Options, "query")
 
@@ -564,7 +676,7 @@ func (b *Bot) handleProjectList(s *discordgo.Session, i *discordgo.InteractionCr
 	}
 
 	if org == "" {
-		settings, err := b.db.GetChannelSettings(i.ChannelID)
+		settings, err := b.getEffectiveSettings(s, i)
 		if err == nil && settings.DefaultRepo != "" {
 			parts := strings.Split(settings.DefaultRepo, "/")
 			if len(parts) == 2 {
@@ -584,16 +696,21 @@ func (b *Bot) handleProjectList(s *discordgo.Session, i *discordgo.InteractionCr
 		return
 	}
 
-	projectsResponse, err := b.githubREST.ListProjects(org, accessToken, 10, query)
+	b.showListing(s, i, func(page url.Values) (*listingPage, error) {
+		return b.fetchProjectsPage(org, accessToken, query, page)
+	})
+}
+
+// fetchProjectsPage loads one page of /gh-project-list results.
+func (b *Bot) fetchProjectsPage(org, accessToken, query string, page url.Values) (*listingPage, error) {
+	projectsResponse, links, err := b.githubREST.ListProjects(org, accessToken, listingPageSize, query, page)
 	if err != nil {
 		log.Printf("Failed to list projects using REST: %v", err)
-		b.respondError(s, i, fmt.Sprintf("Failed to list projects: %v", err))
-		return
+		return nil, fmt.Errorf("Failed to list projects: %v", err)
 	}
 
-	if len(projectsResponse.Projects) == 0 {
-		b.respondSuccess(s, i, fmt.Sprintf("No projects found for organization %s with query: %s", org, query))
-		return
+	if len(projectsResponse.Projects) == 0 && page == nil {
+		return &listingPage{content: fmt.Sprintf("No projects found for organization %s with query: %s", org, query), empty: true}, nil
 	}
 
 	var response strings.Builder
@@ -607,18 +724,20 @@ func (b *Bot) handleProjectList(s *discordgo.Session, i *discordgo.InteractionCr
 		response.WriteString(fmt.Sprintf("**[#%d %s](%s)** (Status: %s)\n", project.Number, project.Title, project.HTMLURL, status))
 	}
 
-	b.respondSuccess(s, i, response.String())
+	return &listingPage{content: response.String(), links: links}, nil
 }
 
 func (b *Bot) handleProjectAddIssue(s *discordgo.Session, i *discordgo.InteractionCreate) {
-	userID := i.Member.User.ID
+	b.deferResponse(s, i, false)
+
+	userID := invokingUser(i).ID
 	issueNumber := b.getIntOption(i.ApplicationCommandData().Options, "issue-number")
 	projectNumber := b.getIntOption(i.ApplicationCommandData().Options, "project-number")
 	repo := b.getStringOption(i.ApplicationCommandData().Options, "repo")
 	org := b.getStringOption(i.ApplicationCommandData().Options, "org")
 
 	if repo == "" {
-		settings, err := b.db.GetChannelSettings(i.ChannelID)
+		settings, err := b.getEffectiveSettings(s, i)
 		if err != nil || settings.DefaultRepo == "" {
 			b.respondError(s, i, "No repository specified and no default repository set for this channel")
 			return
@@ -634,7 +753,7 @@ func (b *Bot) handleProjectAddIssue(s *discordgo.Session, i *discordgo.Interacti
 	owner, repoName := parts[0], parts[1]
 
 	if projectNumber == 0 || org == "" {
-		settings, err := b.db.GetChannelSettings(i.ChannelID)
+		settings, err := b.getEffectiveSettings(s, i)
 		if err != nil || settings.DefaultProject == "" {
 			if org == "" {
 				org = owner
@@ -684,6 +803,7 @@ func (b *Bot) handleProjectAddIssue(s *discordgo.Session, i *discordgo.Interacti
 
 	// Add issue to project using REST API
 	_, err = b.githubREST.AddIssueToProject(org, projectNumber, issueID, accessToken)
+	b.audit(i, &database.AuditEntry{Repo: repo, IssueNumber: issueNumber, Project: fmt.Sprintf("%s/%d", org, projectNumber)}, err)
 	if err != nil {
 		log.Printf("Failed to add issue to project using REST: %v", err)
 		b.respondError(s, i, fmt.Sprintf("Failed to add issue to project: %v", err))
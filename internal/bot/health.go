@@ -0,0 +1,94 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"log"
+	"strings"
+	"time"
+
+	"discord-github-bot/internal/oauth"
+)
+
+// tokenHealthCheckInterval is how often every stored GitHub token is validated.
+const tokenHealthCheckInterval = 6 * time.Hour
+
+// runTokenHealthChecks validates stored tokens on startup and then periodically.
+func (b *Bot) runTokenHealthChecks() {
+	ticker := time.NewTicker(tokenHealthCheckInterval)
+	defer ticker.Stop()
+
+	for {
+		b.checkTokenHealth()
+		<-ticker.C
+	}
+}
+
+// checkTokenHealth marks users whose tokens were revoked or lost scopes as
+// stale and asks them to re-authenticate. Transient errors are only logged so
+// a GitHub outage doesn't flag every user.
+func (b *Bot) checkTokenHealth() {
+	users, err := b.db.ListUsers()
+	if err != nil {
+		log.Printf("Failed to list users for token health check: %v", err)
+		return
+	}
+
+	for _, user := range users {
+		if user.TokenStale {
+			continue
+		}
+
+		err := b.oauth.CheckTokenHealth(user.DiscordID)
+		if err == nil {
+			continue
+		}
+
+		reason, stale := staleTokenReason(err)
+		if !stale {
+			log.Printf("Token health check failed for %s: %v", user.DiscordID, err)
+			continue
+		}
+
+		log.Printf("Marking GitHub token for %s as stale: %v", user.DiscordID, err)
+		if err := b.db.MarkUserStale(user.DiscordID); err != nil {
+			log.Printf("Failed to mark user %s as stale: %v", user.DiscordID, err)
+			continue
+		}
+
+		b.notifyStaleToken(user.DiscordID, user.GitHubUsername, reason)
+	}
+}
+
+// staleTokenReason completes the sentence "can no longer be used because …"
+// for a failed health check. It reports false for errors that don't mean the
+// token is stale, such as GitHub being unreachable.
+func staleTokenReason(err error) (string, bool) {
+	var missingScopes *oauth.MissingScopesError
+	switch {
+	case errors.Is(err, oauth.ErrTokenInvalid):
+		return "your GitHub token has been revoked or has expired", true
+	case errors.As(err, &missingScopes):
+		return fmt.Sprintf("your GitHub token is missing required permissions (%s)", strings.Join(missingScopes.Scopes, ", ")), true
+	default:
+		return "", false
+	}
+}
+
+func (b *Bot) notifyStaleToken(discordID, githubUsername, reason string) {
+	channel, err := b.session.UserChannelCreate(discordID)
+	if err != nil {
+		log.Printf("Failed to open DM with %s: %v", discordID, err)
+		return
+	}
+
+	message := fmt.Sprintf(
+		"⚠️ The GitHub account **%s** linked to this bot can no longer be used because %s.\n\nPlease run `/gh-auth` to link your account again.",
+		githubUsername,
+		reason,
+	)
+
+	if _, err := b.session.ChannelMessageSend(channel.ID, message); err != nil {
+		log.Printf("Failed to DM %s about stale token: %v", discordID, err)
+	}
+}
@@ -0,0 +1,50 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"testing"
+
+	"discord-github-bot/internal/oauth"
+)
+
+func TestStaleTokenReason(t *testing.T) {
+	tests := []struct {
+		name      string
+		err       error
+		want      string
+		wantStale bool
+	}{
+		{
+			name:      "revoked",
+			err:       oauth.ErrTokenInvalid,
+			want:      "your GitHub token has been revoked or has expired",
+			wantStale: true,
+		},
+		{
+			name:      "missing scopes",
+			err:       &oauth.MissingScopesError{Scopes: []string{"read:org", "project"}},
+			want:      "your GitHub token is missing required permissions (read:org, project)",
+			wantStale: true,
+		},
+		{
+			name:      "wrapped missing scopes",
+			err:       fmt.Errorf("checking token: %w", &oauth.MissingScopesError{Scopes: []string{"repo"}}),
+			want:      "your GitHub token is missing required permissions (repo)",
+			wantStale: true,
+		},
+		{
+			name: "GitHub unreachable",
+			err:  errors.New("connection refused"),
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			got, stale := staleTokenReason(tt.err)
+			if got != tt.want || stale != tt.wantStale {
+				t.Errorf("staleTokenReason() = %q, %v; want %q, %v", got, stale, tt.want, tt.wantStale)
+			}
+		})
+	}
+}
@@ -0,0 +1,199 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/google/go-github/v57/github"
+)
+
+const (
+	issueModalCustomIDPrefix = "issue-new:"
+
+	// Discord's limits on a modal's CustomID and text input lengths.
+	maxCustomIDLength  = 100
+	maxIssueTitleInput = 256
+	maxIssueBodyInput  = 4000
+)
+
+func (b *Bot) handleIssueNew(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	repo := b.getStringOption(i.ApplicationCommandData().Options, "repo")
+
+	if repo == "" {
+		settings, err := b.getEffectiveSettings(s, i)
+		if err != nil || settings.DefaultRepo == "" {
+			b.respondError(s, i, "No repository specified and no default repository set for this channel")
+			return
+		}
+		repo = settings.DefaultRepo
+	}
+
+	b.openIssueModal(s, i, repo, "")
+}
+
+// openIssueModal asks for the details of a new issue in repo. The repository
+// travels in the modal's CustomID; the issue is created when it is submitted.
+func (b *Bot) openIssueModal(s *discordgo.Session, i *discordgo.InteractionCreate, repo, title string) {
+	b.showIssueModal(s, i, issueModalCustomIDPrefix+repo, repo, title, "")
+}
+
+// showIssueModal opens the new issue form prefilled with title and body.
+// customID identifies how the submission is handled and must encode repo.
+func (b *Bot) showIssueModal(s *discordgo.Session, i *discordgo.InteractionCreate, customID, repo, title, body string) {
+	if !strings.Contains(repo, "/") {
+		b.respondError(s, i, "Invalid repository format. Use: owner/repo")
+		return
+	}
+
+	if len(customID) > maxCustomIDLength {
+		b.respondError(s, i, "Repository name is too long for the issue form. Pass `body` to `/gh-issue-create` instead.")
+		return
+	}
+
+	// Check before the user writes a long description that can't be submitted.
+	user, err := b.db.GetUser(invokingUser(i).ID)
+	if err != nil || user == nil {
+		b.respondError(s, i, "You must authenticate first. Use /gh-auth")
+		return
+	}
+
+	err = s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseModal,
+		Data: &discordgo.InteractionResponseData{
+			CustomID: customID,
+			Title:    truncate("New issue in "+repo, 44),
+			Components: []discordgo.MessageComponent{
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.TextInput{
+						CustomID:  "title",
+						Label:     "Title",
+						Style:     discordgo.TextInputShort,
+						Value:     truncate(title, maxIssueTitleInput-1),
+						Required:  true,
+						MaxLength: maxIssueTitleInput,
+					},
+				}},
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.TextInput{
+						CustomID:    "body",
+						Label:       "Description",
+						Style:       discordgo.TextInputParagraph,
+						Placeholder: "Steps to reproduce, stack traces, checklists… Markdown is supported.",
+						Value:       truncate(body, maxIssueBodyInput-1),
+						Required:    false,
+						MaxLength:   maxIssueBodyInput,
+					},
+				}},
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.TextInput{
+						CustomID:    "labels",
+						Label:       "Labels",
+						Style:       discordgo.TextInputShort,
+						Placeholder: "bug, needs-triage",
+						Required:    false,
+					},
+				}},
+				discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+					discordgo.TextInput{
+						CustomID:    "assignees",
+						Label:       "Assignees",
+						Style:       discordgo.TextInputShort,
+						Placeholder: "octocat, hubot",
+						Required:    false,
+					},
+				}},
+			},
+		},
+	})
+	if err != nil {
+		log.Printf("Failed to open issue modal: %v", err)
+	}
+}
+
+func (b *Bot) handleIssueModalSubmit(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	data := i.ModalSubmitData()
+	repo := strings.TrimPrefix(data.CustomID, issueModalCustomIDPrefix)
+
+	b.deferResponse(s, i, false)
+
+	issue, err := issueRequestFromModal(data)
+	if err != nil {
+		b.respondError(s, i, err.Error())
+		return
+	}
+
+	if createdIssue := b.createIssue(s, i, "gh-issue-new", repo, issue); createdIssue != nil {
+		b.respondIssueCreated(s, i, createdIssue)
+	}
+}
+
+// issueRequestFromModal reads the fields of a submitted issue form.
+func issueRequestFromModal(data discordgo.ModalSubmitInteractionData) (*github.IssueRequest, error) {
+	title := strings.TrimSpace(modalValue(data, "title"))
+	body := modalValue(data, "body")
+
+	if title == "" {
+		return nil, fmt.Errorf("Issue title is required")
+	}
+
+	issue := &github.IssueRequest{
+		Title: &title,
+		Body:  &body,
+	}
+	if labels := splitList(modalValue(data, "labels"), ""); len(labels) > 0 {
+		issue.Labels = &labels
+	}
+	if assignees := splitList(modalValue(data, "assignees"), "@"); len(assignees) > 0 {
+		issue.Assignees = &assignees
+	}
+
+	return issue, nil
+}
+
+// modalValue returns the value of the text input with customID in a submitted modal.
+func modalValue(data discordgo.ModalSubmitInteractionData, customID string) string {
+	for _, component := range data.Components {
+		row, ok := component.(*discordgo.ActionsRow)
+		if !ok {
+			continue
+		}
+		for _, c := range row.Components {
+			if input, ok := c.(*discordgo.TextInput); ok && input.CustomID == customID {
+				return input.Value
+			}
+		}
+	}
+	return ""
+}
+
+// splitList splits a comma-separated input into trimmed, non-empty items,
+// removing prefix from each.
+func splitList(value, prefix string) []string {
+	var items []string
+	for _, item := range strings.Split(value, ",") {
+		item = strings.TrimSpace(item)
+		if prefix != "" {
+			item = strings.TrimPrefix(item, prefix)
+		}
+		if item != "" {
+			items = append(items, item)
+		}
+	}
+	return items
+}
+
+// handleModalSubmit dispatches modal submissions by CustomID prefix.
+func (b *Bot) handleModalSubmit(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	customID := i.ModalSubmitData().CustomID
+
+	switch {
+	case strings.HasPrefix(customID, issueModalCustomIDPrefix):
+		b.handleIssueModalSubmit(s, i)
+	case strings.HasPrefix(customID, messageIssueCustomIDPrefix):
+		b.handleMessageIssueSubmit(s, i)
+	default:
+		b.respondError(s, i, fmt.Sprintf("Unknown form: %s", customID))
+	}
+}
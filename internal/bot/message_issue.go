@@ -0,0 +1,179 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strings"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/google/go-github/v57/github"
+)
+
+const (
+	// messageIssueCommand is the message context-menu command's name as shown in Discord.
+	messageIssueCommand = "Create GitHub issue"
+
+	messageIssueCustomIDPrefix = "issue-msg:"
+
+	// maxIssueTitleFromMessage keeps titles taken from a message's first line short.
+	maxIssueTitleFromMessage = 80
+
+	// maxQuotedMessageLength caps how much of a message is quoted in the form's
+	// body. Less is quoted when its attachments need the room.
+	maxQuotedMessageLength = 3000
+
+	// threadAutoArchiveMinutes is how long the thread started for an issue stays open without activity.
+	threadAutoArchiveMinutes = 1440
+)
+
+// handleMessageIssue opens the new issue form prefilled from the message the
+// command was used on. The form's CustomID carries the message ID and repo.
+func (b *Bot) handleMessageIssue(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	data := i.ApplicationCommandData()
+
+	var message *discordgo.Message
+	if data.Resolved != nil {
+		message = data.Resolved.Messages[data.TargetID]
+	}
+	if message == nil {
+		b.respondError(s, i, "Could not read the selected message")
+		return
+	}
+
+	settings, err := b.getEffectiveSettings(s, i)
+	if err != nil || settings.DefaultRepo == "" {
+		b.respondError(s, i, "No default repository set for this channel. Use /gh-set-repo first.")
+		return
+	}
+	repo := settings.DefaultRepo
+
+	customID := fmt.Sprintf("%s%s:%s", messageIssueCustomIDPrefix, message.ID, repo)
+	b.showIssueModal(s, i, customID, repo, messageIssueTitle(message), messageIssueBody(i, message))
+}
+
+// messageIssueTitle suggests a title from the first line of message.
+func messageIssueTitle(message *discordgo.Message) string {
+	firstLine, _, _ := strings.Cut(strings.TrimSpace(message.Content), "\n")
+	return truncate(strings.TrimSpace(firstLine), maxIssueTitleFromMessage)
+}
+
+// messageIssueBody quotes message and links back to it and its attachments.
+// The quote is shortened to whatever room the attachments leave, so the body
+// fits in the form and the link back is never cut off.
+func messageIssueBody(i *discordgo.InteractionCreate, message *discordgo.Message) string {
+	author := "unknown user"
+	if message.Author != nil {
+		author = message.Author.Username
+	}
+
+	guildID := i.GuildID
+	if guildID == "" {
+		guildID = "@me"
+	}
+	jumpURL := fmt.Sprintf("https://discord.com/channels/%s/%s/%s", guildID, message.ChannelID, message.ID)
+	footer := fmt.Sprintf("---\n_Reported by **%s** on Discord: %s_", author, jumpURL)
+
+	// showIssueModal cuts the body at maxIssueBodyInput-1 characters.
+	room := maxIssueBodyInput - 1 - utf8.RuneCountInString(footer)
+
+	var attachments strings.Builder
+	if len(message.Attachments) > 0 {
+		attachments.WriteString("**Attachments:**\n")
+		for n, attachment := range message.Attachments {
+			line := fmt.Sprintf("- [%s](%s)\n", attachment.Filename, attachment.URL)
+			more := fmt.Sprintf("- …and %d more\n", len(message.Attachments)-n)
+			if utf8.RuneCountInString(attachments.String()+line+more)+1 > room {
+				attachments.WriteString(more)
+				break
+			}
+			attachments.WriteString(line)
+		}
+		attachments.WriteString("\n")
+	}
+	room -= utf8.RuneCountInString(attachments.String())
+
+	var body strings.Builder
+
+	// Leave room for the blank line after the quote and truncate's ellipsis.
+	if quoteRoom := min(maxQuotedMessageLength, room-3); message.Content != "" && quoteRoom > 0 {
+		body.WriteString(truncate(message.Content, quoteRoom))
+		body.WriteString("\n\n")
+	}
+
+	body.WriteString(attachments.String())
+	body.WriteString(footer)
+
+	return body.String()
+}
+
+func (b *Bot) handleMessageIssueSubmit(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	data := i.ModalSubmitData()
+	messageID, repo, found := strings.Cut(strings.TrimPrefix(data.CustomID, messageIssueCustomIDPrefix), ":")
+	if !found {
+		b.respondError(s, i, "Invalid form")
+		return
+	}
+
+	b.deferResponse(s, i, true)
+
+	issue, err := issueRequestFromModal(data)
+	if err != nil {
+		b.respondError(s, i, err.Error())
+		return
+	}
+
+	createdIssue := b.createIssue(s, i, messageIssueCommand, repo, issue)
+	if createdIssue == nil {
+		return
+	}
+
+	if err := b.replyInThread(s, i.ChannelID, messageID, createdIssue); err != nil {
+		// The bot may not be in this channel (DMs, user installs) or lack thread permissions.
+		log.Printf("Failed to reply in thread for issue #%d: %v", createdIssue.GetNumber(), err)
+		b.respondIssueCreated(s, i, createdIssue)
+		return
+	}
+
+	b.respondEphemeral(s, i, fmt.Sprintf("✅ Created issue **#%d** and linked it in a thread on the message.", createdIssue.GetNumber()))
+}
+
+// replyInThread posts a link to issue in the thread for messageID, starting
+// one if needed. If the message is itself in a thread it is replied to there.
+func (b *Bot) replyInThread(s *discordgo.Session, channelID, messageID string, issue *github.Issue) error {
+	content := fmt.Sprintf("📝 Tracked in GitHub issue **#%d** %s\n%s", issue.GetNumber(), issue.GetTitle(), issue.GetHTMLURL())
+
+	channel, err := b.lookupChannel(s, channelID)
+	if err != nil {
+		return err
+	}
+
+	if channel.IsThread() {
+		_, err := s.ChannelMessageSendReply(channelID, content, &discordgo.MessageReference{
+			MessageID: messageID,
+			ChannelID: channelID,
+			GuildID:   channel.GuildID,
+		})
+		return err
+	}
+
+	message, err := s.ChannelMessage(channelID, messageID)
+	if err != nil {
+		return err
+	}
+
+	threadID := ""
+	if message.Thread != nil {
+		threadID = message.Thread.ID
+	} else {
+		name := truncate(fmt.Sprintf("#%d %s", issue.GetNumber(), issue.GetTitle()), 99)
+		thread, err := s.MessageThreadStart(channelID, messageID, name, threadAutoArchiveMinutes)
+		if err != nil {
+			return err
+		}
+		threadID = thread.ID
+	}
+
+	_, err = s.ChannelMessageSend(threadID, content)
+	return err
+}
@@ -0,0 +1,71 @@
+package bot
+
+import (
+	"fmt"
+	"strings"
+	"testing"
+	"unicode/utf8"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func testAttachments(n int) []*discordgo.MessageAttachment {
+	attachments := make([]*discordgo.MessageAttachment, n)
+	for i := range attachments {
+		filename := fmt.Sprintf("screenshot-%03d.png", i)
+		attachments[i] = &discordgo.MessageAttachment{
+			Filename: filename,
+			URL:      "https://cdn.discordapp.com/attachments/111/222/" + filename,
+		}
+	}
+	return attachments
+}
+
+func TestMessageIssueBody(t *testing.T) {
+	tests := []struct {
+		name        string
+		content     string
+		attachments int
+		wantQuote   bool
+		wantMore    bool
+	}{
+		{name: "short message", content: "The build is broken", wantQuote: true},
+		{name: "no content", attachments: 2},
+		{name: "long message", content: strings.Repeat("a", 4000), wantQuote: true},
+		{name: "long multibyte message", content: strings.Repeat("é", 4000), wantQuote: true},
+		{name: "few attachments", content: "see attached", attachments: 3, wantQuote: true},
+		{name: "many attachments and a long message", content: strings.Repeat("a", 4000), attachments: 200, wantQuote: true, wantMore: true},
+	}
+
+	i := &discordgo.InteractionCreate{Interaction: &discordgo.Interaction{GuildID: "1"}}
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			message := &discordgo.Message{
+				ID:          "3",
+				ChannelID:   "2",
+				Content:     tt.content,
+				Author:      &discordgo.User{Username: "octocat"},
+				Attachments: testAttachments(tt.attachments),
+			}
+
+			body := messageIssueBody(i, message)
+
+			if n := utf8.RuneCountInString(body); n > maxIssueBodyInput-1 {
+				t.Errorf("body is %d characters, want at most %d", n, maxIssueBodyInput-1)
+			}
+			footer := "---\n_Reported by **octocat** on Discord: https://discord.com/channels/1/2/3_"
+			if !strings.HasSuffix(body, footer) {
+				t.Errorf("body does not end with the footer and jump URL:\n%s", body)
+			}
+			if got := tt.content != "" && strings.HasPrefix(body, tt.content[:1]); got != tt.wantQuote {
+				t.Errorf("body quotes the message = %v, want %v", got, tt.wantQuote)
+			}
+			if got := strings.Contains(body, "more\n"); got != tt.wantMore {
+				t.Errorf("body summarises remaining attachments = %v, want %v", got, tt.wantMore)
+			}
+			if tt.attachments > 0 && !strings.Contains(body, "[screenshot-000.png](https://cdn.discordapp.com/attachments/111/222/screenshot-000.png)") {
+				t.Errorf("body does not link the first attachment:\n%s", body)
+			}
+		})
+	}
+}
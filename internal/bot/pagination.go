@@ -0,0 +1,277 @@
+package bot
+
+import (
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log"
+	"net/url"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"discord-github-bot/internal/github/rest"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/google/go-github/v57/github"
+)
+
+const (
+	// listingPageSize is how many results each page of a listing shows.
+	listingPageSize = 10
+
+	// listingTTL is how long the buttons on a listing keep working.
+	listingTTL = 30 * time.Minute
+
+	// maxJumpOptions is Discord's limit on options in a select menu.
+	maxJumpOptions = 25
+
+	listingCustomIDPrefix = "list:"
+)
+
+// listingPage is one rendered page of a listing and the links to its neighbours.
+type listingPage struct {
+	content string
+	empty   bool
+	links   rest.PageLinks
+}
+
+// listing is a paginated command result. Its parameters and the page cursors
+// seen so far are kept in memory; the buttons only carry the listing ID and
+// the page number, so they fit in a CustomID.
+type listing struct {
+	ownerID string
+	fetch   func(page url.Values) (*listingPage, error)
+
+	mu        sync.Mutex
+	pages     map[int]url.Values
+	lastPage  int
+	expiresAt time.Time
+}
+
+// showListing renders the first page of fetch and stores the listing so its
+// buttons can load other pages. Only the invoking user can use the buttons.
+func (b *Bot) showListing(s *discordgo.Session, i *discordgo.InteractionCreate, fetch func(page url.Values) (*listingPage, error)) {
+	l := &listing{
+		ownerID:   invokingUser(i).ID,
+		fetch:     fetch,
+		pages:     map[int]url.Values{1: nil},
+		expiresAt: time.Now().Add(listingTTL),
+	}
+
+	page, err := fetch(nil)
+	if err != nil {
+		b.respondError(s, i, err.Error())
+		return
+	}
+
+	if page.empty {
+		b.respondSuccess(s, i, page.content)
+		return
+	}
+
+	id := b.storeListing(l)
+	b.respond(s, i, l.render(id, 1, page))
+}
+
+func (b *Bot) storeListing(l *listing) string {
+	now := time.Now()
+	b.listings.Range(func(key, value any) bool {
+		if value.(*listing).expiresAt.Before(now) {
+			b.listings.Delete(key)
+		}
+		return true
+	})
+
+	buf := make([]byte, 8)
+	rand.Read(buf)
+	id := hex.EncodeToString(buf)
+
+	b.listings.Store(id, l)
+	return id
+}
+
+// render records the links from page and returns the message for page number n.
+func (l *listing) render(id string, n int, page *listingPage) *discordgo.InteractionResponseData {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if next, ok := page.links["next"]; ok {
+		l.pages[n+1] = next
+	}
+	if prev, ok := page.links["prev"]; ok && n > 1 {
+		l.pages[n-1] = prev
+	}
+	if last, ok := page.links["last"]; ok {
+		if lastPage, err := strconv.Atoi(last.Get("page")); err == nil {
+			l.lastPage = lastPage
+		}
+	}
+	if n > l.lastPage && page.links["next"] == nil {
+		l.lastPage = n
+	}
+
+	_, hasPrev := l.pages[n-1]
+	_, hasNext := l.pages[n+1]
+
+	pageLabel := fmt.Sprintf("Page %d", n)
+	if l.lastPage > 0 {
+		pageLabel = fmt.Sprintf("Page %d of %d", n, l.lastPage)
+	}
+
+	components := []discordgo.MessageComponent{
+		discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.Button{
+				Label:    "◀ Previous",
+				Style:    discordgo.SecondaryButton,
+				CustomID: listingCustomID(id, strconv.Itoa(n-1)),
+				Disabled: !hasPrev,
+			},
+			discordgo.Button{
+				Label:    pageLabel,
+				Style:    discordgo.SecondaryButton,
+				CustomID: listingCustomID(id, "current"),
+				Disabled: true,
+			},
+			discordgo.Button{
+				Label:    "Next ▶",
+				Style:    discordgo.SecondaryButton,
+				CustomID: listingCustomID(id, strconv.Itoa(n+1)),
+				Disabled: !hasNext,
+			},
+		}},
+	}
+
+	// Only numbered pages can be jumped to; cursor-based listings are walked one page at a time.
+	if l.lastPage > 1 && l.numbered() {
+		components = append(components, discordgo.ActionsRow{Components: []discordgo.MessageComponent{
+			discordgo.SelectMenu{
+				CustomID:    listingCustomID(id, "jump"),
+				Placeholder: "Jump to page…",
+				Options:     jumpOptions(n, l.lastPage),
+			},
+		}})
+	}
+
+	return &discordgo.InteractionResponseData{
+		Content:    page.content,
+		Components: components,
+	}
+}
+
+func (l *listing) numbered() bool {
+	for _, page := range l.pages {
+		if page.Get("page") != "" {
+			return true
+		}
+	}
+	return false
+}
+
+// pageParams returns the parameters that load page n, if known.
+func (l *listing) pageParams(n int) (url.Values, bool) {
+	l.mu.Lock()
+	defer l.mu.Unlock()
+
+	if params, ok := l.pages[n]; ok {
+		return params, true
+	}
+
+	if l.numbered() && n >= 1 && n <= l.lastPage {
+		return url.Values{"page": {strconv.Itoa(n)}}, true
+	}
+
+	return nil, false
+}
+
+// jumpOptions lists up to maxJumpOptions pages centred on current.
+func jumpOptions(current, last int) []discordgo.SelectMenuOption {
+	first := max(1, current-maxJumpOptions/2)
+	end := min(last, first+maxJumpOptions-1)
+	first = max(1, end-maxJumpOptions+1)
+
+	options := make([]discordgo.SelectMenuOption, 0, end-first+1)
+	for n := first; n <= end; n++ {
+		options = append(options, discordgo.SelectMenuOption{
+			Label:   fmt.Sprintf("Page %d", n),
+			Value:   strconv.Itoa(n),
+			Default: n == current,
+		})
+	}
+	return options
+}
+
+func listingCustomID(id, action string) string {
+	return listingCustomIDPrefix + id + ":" + action
+}
+
+func (b *Bot) handleListingComponent(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	data := i.MessageComponentData()
+	id, action, _ := strings.Cut(strings.TrimPrefix(data.CustomID, listingCustomIDPrefix), ":")
+
+	value, ok := b.listings.Load(id)
+	if !ok || value.(*listing).expiresAt.Before(time.Now()) {
+		b.respondError(s, i, "This list has expired. Run the command again to get a fresh one.")
+		return
+	}
+	l := value.(*listing)
+
+	if invokingUser(i).ID != l.ownerID {
+		b.respondError(s, i, "Only the person who ran this command can change its page. Run the command yourself to browse the results.")
+		return
+	}
+
+	if action == "jump" && len(data.Values) > 0 {
+		action = data.Values[0]
+	}
+
+	n, err := strconv.Atoi(action)
+	if err != nil {
+		b.respondError(s, i, "Unknown page")
+		return
+	}
+
+	params, ok := l.pageParams(n)
+	if !ok {
+		b.respondError(s, i, fmt.Sprintf("Page %d is not available", n))
+		return
+	}
+
+	b.deferUpdate(s, i)
+
+	page, err := l.fetch(params)
+	if err != nil {
+		log.Printf("Failed to load page %d of listing %s: %v", n, id, err)
+		b.respondError(s, i, err.Error())
+		return
+	}
+
+	b.respond(s, i, l.render(id, n, page))
+}
+
+// githubPageLinks converts go-github's parsed Link header into PageLinks.
+func githubPageLinks(resp *github.Response) rest.PageLinks {
+	links := rest.PageLinks{}
+	add := func(rel string, page int) {
+		if page != 0 {
+			links[rel] = url.Values{"page": {strconv.Itoa(page)}}
+		}
+	}
+
+	add("next", resp.NextPage)
+	add("prev", resp.PrevPage)
+	add("first", resp.FirstPage)
+	add("last", resp.LastPage)
+
+	return links
+}
+
+// listPage returns the page number selected by params, defaulting to the first.
+func listPage(params url.Values) int {
+	page, err := strconv.Atoi(params.Get("page"))
+	if err != nil {
+		return 1
+	}
+	return page
+}
@@ -0,0 +1,112 @@
+package bot
+
+import (
+	"net/url"
+	"strconv"
+	"testing"
+
+	"discord-github-bot/internal/github/rest"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// renderedButtons returns the page label and whether the previous and next
+// buttons are enabled in a rendered listing page.
+func renderedButtons(t *testing.T, data *discordgo.InteractionResponseData) (label string, prev, next bool) {
+	row, ok := data.Components[0].(discordgo.ActionsRow)
+	if !ok || len(row.Components) != 3 {
+		t.Fatalf("first component = %#v, want a row of three buttons", data.Components[0])
+	}
+	prevButton := row.Components[0].(discordgo.Button)
+	labelButton := row.Components[1].(discordgo.Button)
+	nextButton := row.Components[2].(discordgo.Button)
+	return labelButton.Label, !prevButton.Disabled, !nextButton.Disabled
+}
+
+func newTestListing() *listing {
+	return &listing{pages: map[int]url.Values{1: nil}}
+}
+
+func TestListingRenderNextOnly(t *testing.T) {
+	l := newTestListing()
+
+	// Cursor-based listings only say whether there is a next page.
+	data := l.render("id", 1, &listingPage{links: rest.PageLinks{"next": {"after": {"c1"}}}})
+	label, prev, next := renderedButtons(t, data)
+	if label != "Page 1" || prev || !next {
+		t.Fatalf("page 1 = %q, prev %v, next %v; want Page 1 with only next", label, prev, next)
+	}
+	if len(data.Components) != 1 {
+		t.Fatalf("page 1 has %d rows, want no jump menu for a cursor listing", len(data.Components))
+	}
+
+	params, ok := l.pageParams(2)
+	if !ok || params.Get("after") != "c1" {
+		t.Fatalf("pageParams(2) = %v, %v; want the next cursor", params, ok)
+	}
+	if _, ok := l.pageParams(3); ok {
+		t.Fatal("pageParams(3) is known before page 2 was loaded")
+	}
+
+	// The last page has no next link, which reveals the page count.
+	data = l.render("id", 2, &listingPage{links: rest.PageLinks{"prev": {"before": {"c2"}}}})
+	label, prev, next = renderedButtons(t, data)
+	if label != "Page 2 of 2" || !prev || next {
+		t.Fatalf("page 2 = %q, prev %v, next %v; want Page 2 of 2 with only previous", label, prev, next)
+	}
+}
+
+func TestListingRenderPrevAndLast(t *testing.T) {
+	l := newTestListing()
+	l.pages[3] = url.Values{"page": {"3"}}
+
+	data := l.render("id", 3, &listingPage{links: rest.PageLinks{
+		"prev": {"page": {"2"}},
+		"next": {"page": {"4"}},
+		"last": {"page": {"7"}},
+	}})
+	label, prev, next := renderedButtons(t, data)
+	if label != "Page 3 of 7" || !prev || !next {
+		t.Fatalf("page 3 = %q, prev %v, next %v; want Page 3 of 7 with both buttons", label, prev, next)
+	}
+	if len(data.Components) != 2 {
+		t.Fatalf("page 3 has %d rows, want a jump menu for a numbered listing", len(data.Components))
+	}
+
+	// Numbered pages that were never linked can still be jumped to.
+	if params, ok := l.pageParams(6); !ok || params.Get("page") != "6" {
+		t.Fatalf("pageParams(6) = %v, %v; want page 6", params, ok)
+	}
+	if _, ok := l.pageParams(8); ok {
+		t.Fatal("pageParams(8) is past the last page")
+	}
+}
+
+func TestListingRenderWithoutLinks(t *testing.T) {
+	l := newTestListing()
+
+	// A missing or malformed Link header leaves a single page.
+	label, prev, next := renderedButtons(t, l.render("id", 1, &listingPage{links: rest.PageLinks{}}))
+	if label != "Page 1 of 1" || prev || next {
+		t.Fatalf("page 1 = %q, prev %v, next %v; want Page 1 of 1 with no buttons", label, prev, next)
+	}
+}
+
+func TestJumpOptions(t *testing.T) {
+	tests := []struct {
+		current, last int
+		first, end    int
+	}{
+		{1, 5, 1, 5},
+		{1, 100, 1, maxJumpOptions},
+		{50, 100, 50 - maxJumpOptions/2, 50 - maxJumpOptions/2 + maxJumpOptions - 1},
+		{100, 100, 100 - maxJumpOptions + 1, 100},
+	}
+
+	for _, tt := range tests {
+		options := jumpOptions(tt.current, tt.last)
+		if len(options) != tt.end-tt.first+1 || options[0].Value != strconv.Itoa(tt.first) || options[len(options)-1].Value != strconv.Itoa(tt.end) {
+			t.Errorf("jumpOptions(%d, %d) = %s..%s, want %d..%d", tt.current, tt.last, options[0].Value, options[len(options)-1].Value, tt.first, tt.end)
+		}
+	}
+}
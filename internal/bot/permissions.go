@@ -0,0 +1,364 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"sort"
+	"strings"
+
+	"discord-github-bot/internal/database"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// Actions that can be restricted per guild with /gh-admin.
+const (
+	actionSettings = "settings"
+	actionClose    = "close"
+	actionCreate   = "create"
+)
+
+// commandActions maps commands to the action a member needs to run them.
+// Commands that are not listed are available to everyone.
+var commandActions = map[string]string{
+	"gh-set-repo":       actionSettings,
+	"gh-set-project":    actionSettings,
+	"gh-subscribe":      actionSettings,
+	"gh-unsubscribe":    actionSettings,
+	"gh-forum-sync":     actionSettings,
+	"gh-unfurl":         actionSettings,
+	"gh-issue-close":    actionClose,
+	"gh-issue-create":   actionCreate,
+	"gh-issue-new":      actionCreate,
+	messageIssueCommand: actionCreate,
+}
+
+// defaultActionPermissions is the policy for actions a guild has not configured.
+// Actions without an entry are open to everyone.
+var defaultActionPermissions = map[string]int64{
+	actionSettings: discordgo.PermissionManageChannels,
+}
+
+// adminPermission is required to run adminCommands. It cannot be changed.
+const adminPermission = discordgo.PermissionManageServer
+
+var adminCommands = map[string]bool{
+	"gh-admin": true,
+	"gh-audit": true,
+}
+
+// permissionNames are the Discord permissions /gh-admin can grant an action to.
+var permissionNames = map[string]int64{
+	"manage-server":    discordgo.PermissionManageServer,
+	"manage-channels":  discordgo.PermissionManageChannels,
+	"manage-messages":  discordgo.PermissionManageMessages,
+	"manage-threads":   discordgo.PermissionManageThreads,
+	"manage-roles":     discordgo.PermissionManageRoles,
+	"moderate-members": discordgo.PermissionModerateMembers,
+	"send-messages":    discordgo.PermissionSendMessages,
+}
+
+// authorize reports whether the member who sent i may run its command. Outside
+// a guild that has installed the bot there is no policy to enforce, so every
+// command is allowed.
+func (b *Bot) authorize(i *discordgo.InteractionCreate) (bool, error) {
+	if i.Member == nil || !guildInstalled(i) {
+		return true, nil
+	}
+
+	if i.Member.Permissions&discordgo.PermissionAdministrator != 0 {
+		return true, nil
+	}
+
+	name := i.ApplicationCommandData().Name
+	if adminCommands[name] {
+		return i.Member.Permissions&adminPermission != 0, nil
+	}
+
+	action, ok := commandActions[name]
+	if !ok {
+		return true, nil
+	}
+
+	// Anyone may change their own personal defaults.
+	if action == actionSettings && b.getStringOption(i.ApplicationCommandData().Options, "scope") == "personal" {
+		return true, nil
+	}
+
+	return b.memberAllowed(i.GuildID, i.Member, action)
+}
+
+// memberAllowed reports whether member may perform action in guildID under the
+// guild's policy. member.Permissions must hold the member's permissions in the
+// channel the action applies to.
+func (b *Bot) memberAllowed(guildID string, member *discordgo.Member, action string) (bool, error) {
+	if member.Permissions&discordgo.PermissionAdministrator != 0 {
+		return true, nil
+	}
+
+	rules, err := b.db.ListPermissionRules(guildID)
+	if err != nil {
+		return false, err
+	}
+
+	configured := false
+	for _, rule := range rules {
+		if rule.Action != action {
+			continue
+		}
+		configured = true
+		if memberMatchesRule(guildID, member, rule) {
+			return true, nil
+		}
+	}
+
+	if !configured {
+		required := defaultActionPermissions[action]
+		return member.Permissions&required == required, nil
+	}
+
+	return false, nil
+}
+
+// channelMember returns userID's membership in guildID with Permissions set to
+// their permissions in channelID, as interactions provide it. Threads should
+// pass their parent channel, where permission overwrites live.
+func (b *Bot) channelMember(s *discordgo.Session, guildID, channelID, userID string) (*discordgo.Member, error) {
+	if _, err := s.State.Member(guildID, userID); err != nil {
+		member, err := s.GuildMember(guildID, userID)
+		if err != nil {
+			return nil, err
+		}
+		if err := s.State.MemberAdd(member); err != nil {
+			return nil, err
+		}
+	}
+
+	member, err := s.State.Member(guildID, userID)
+	if err != nil {
+		return nil, err
+	}
+	permissions, err := s.State.UserChannelPermissions(userID, channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	withPermissions := *member
+	withPermissions.Permissions = permissions
+	return &withPermissions, nil
+}
+
+// memberMatchesRule reports whether member satisfies rule. The @everyone role
+// shares the guild's ID and matches every member.
+func memberMatchesRule(guildID string, member *discordgo.Member, rule *database.PermissionRule) bool {
+	if rule.RoleID != "" {
+		if rule.RoleID == guildID {
+			return true
+		}
+		for _, role := range member.Roles {
+			if role == rule.RoleID {
+				return true
+			}
+		}
+		return false
+	}
+
+	return rule.Permission != 0 && member.Permissions&rule.Permission == rule.Permission
+}
+
+func (b *Bot) handleAdmin(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if !guildInstalled(i) {
+		b.respondError(s, i, "This command can only be used in a server")
+		return
+	}
+
+	options := i.ApplicationCommandData().Options
+	if len(options) == 0 {
+		b.respondError(s, i, "Unknown subcommand")
+		return
+	}
+	sub := options[0]
+
+	switch sub.Name {
+	case "allow", "revoke":
+		rule, err := b.permissionRuleFromOptions(i.GuildID, sub.Options)
+		if err != nil {
+			b.respondError(s, i, err.Error())
+			return
+		}
+
+		if sub.Name == "allow" {
+			err := b.db.AddPermissionRule(rule)
+			b.audit(i, &database.AuditEntry{Details: fmt.Sprintf("allowed %s to use %s commands", describePermissionRule(rule), rule.Action)}, err)
+			if err != nil {
+				log.Printf("Failed to add permission rule: %v", err)
+				b.respondError(s, i, "Failed to save permission")
+				return
+			}
+			b.respondEphemeral(s, i, fmt.Sprintf("✅ %s may now use **%s** commands.", describePermissionRule(rule), rule.Action))
+			return
+		}
+
+		removed, err := b.db.RemovePermissionRule(rule)
+		if err != nil || removed {
+			b.audit(i, &database.AuditEntry{Details: fmt.Sprintf("revoked %s from %s commands", describePermissionRule(rule), rule.Action)}, err)
+		}
+		if err != nil {
+			log.Printf("Failed to remove permission rule: %v", err)
+			b.respondError(s, i, "Failed to remove permission")
+			return
+		}
+		if !removed {
+			b.respondError(s, i, fmt.Sprintf("%s was not allowed to use %s commands", describePermissionRule(rule), rule.Action))
+			return
+		}
+		b.respondEphemeral(s, i, fmt.Sprintf("✅ %s may no longer use **%s** commands.", describePermissionRule(rule), rule.Action))
+	case "reset":
+		action := b.getStringOption(sub.Options, "action")
+		err := b.db.ClearPermissionRules(i.GuildID, action)
+		b.audit(i, &database.AuditEntry{Details: fmt.Sprintf("reset %s commands to the default policy", action)}, err)
+		if err != nil {
+			log.Printf("Failed to clear permission rules: %v", err)
+			b.respondError(s, i, "Failed to reset permissions")
+			return
+		}
+		b.respondEphemeral(s, i, fmt.Sprintf("✅ **%s** commands are back to the default policy: %s.", action, describeDefaultPolicy(action)))
+	case "list":
+		b.handleAdminList(s, i)
+	default:
+		b.respondError(s, i, "Unknown subcommand")
+	}
+}
+
+func (b *Bot) handleAdminList(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	rules, err := b.db.ListPermissionRules(i.GuildID)
+	if err != nil {
+		log.Printf("Failed to list permission rules: %v", err)
+		b.respondError(s, i, "Failed to list permissions")
+		return
+	}
+
+	byAction := make(map[string][]string)
+	for _, rule := range rules {
+		byAction[rule.Action] = append(byAction[rule.Action], describePermissionRule(rule))
+	}
+
+	var response strings.Builder
+	response.WriteString("**Command permissions for this server:**\n\n")
+	for _, action := range []string{actionSettings, actionClose, actionCreate} {
+		allowed := byAction[action]
+		if len(allowed) == 0 {
+			response.WriteString(fmt.Sprintf("**%s:** %s _(default)_\n", action, describeDefaultPolicy(action)))
+			continue
+		}
+		response.WriteString(fmt.Sprintf("**%s:** %s\n", action, strings.Join(allowed, ", ")))
+	}
+	response.WriteString("\nAdministrators can always run every command, and `/gh-admin` and `/gh-audit` require Manage Server.")
+
+	b.respondEphemeral(s, i, response.String())
+}
+
+// permissionRuleFromOptions builds a rule from the action, role and permission
+// options of /gh-admin allow and /gh-admin revoke.
+func (b *Bot) permissionRuleFromOptions(guildID string, options []*discordgo.ApplicationCommandInteractionDataOption) (*database.PermissionRule, error) {
+	rule := &database.PermissionRule{
+		GuildID: guildID,
+		Action:  b.getStringOption(options, "action"),
+	}
+
+	for _, opt := range options {
+		if opt.Name == "role" {
+			rule.RoleID = opt.RoleValue(nil, guildID).ID
+		}
+	}
+
+	if name := b.getStringOption(options, "permission"); name != "" {
+		permission, ok := permissionNames[name]
+		if !ok {
+			return nil, fmt.Errorf("unknown permission: %s", name)
+		}
+		rule.Permission = permission
+	}
+
+	if (rule.RoleID == "") == (rule.Permission == 0) {
+		return nil, fmt.Errorf("specify either a role or a permission")
+	}
+
+	return rule, nil
+}
+
+func describePermissionRule(rule *database.PermissionRule) string {
+	if rule.RoleID != "" {
+		if rule.RoleID == rule.GuildID {
+			return "@everyone"
+		}
+		return fmt.Sprintf("<@&%s>", rule.RoleID)
+	}
+	return fmt.Sprintf("members with %s", permissionName(rule.Permission))
+}
+
+func describeDefaultPolicy(action string) string {
+	if required, ok := defaultActionPermissions[action]; ok {
+		return fmt.Sprintf("members with %s", permissionName(required))
+	}
+	return "everyone"
+}
+
+func permissionName(permission int64) string {
+	var names []string
+	for name, bit := range permissionNames {
+		if bit == permission {
+			return name
+		}
+		if permission&bit != 0 {
+			names = append(names, name)
+		}
+	}
+	if len(names) == 0 {
+		return fmt.Sprintf("permission %d", permission)
+	}
+	sort.Strings(names)
+	return strings.Join(names, " + ")
+}
+
+// permissionRuleOptions are the options shared by /gh-admin allow and revoke.
+func permissionRuleOptions() []*discordgo.ApplicationCommandOption {
+	permissionChoices := make([]*discordgo.ApplicationCommandOptionChoice, 0, len(permissionNames))
+	for name := range permissionNames {
+		permissionChoices = append(permissionChoices, &discordgo.ApplicationCommandOptionChoice{Name: name, Value: name})
+	}
+	sort.Slice(permissionChoices, func(a, b int) bool {
+		return permissionChoices[a].Name < permissionChoices[b].Name
+	})
+
+	return []*discordgo.ApplicationCommandOption{
+		actionOption(),
+		{
+			Type:        discordgo.ApplicationCommandOptionRole,
+			Name:        "role",
+			Description: "Role to allow (use @everyone to open the action to all members)",
+			Required:    false,
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "permission",
+			Description: "Discord permission to allow",
+			Required:    false,
+			Choices:     permissionChoices,
+		},
+	}
+}
+
+func actionOption() *discordgo.ApplicationCommandOption {
+	return &discordgo.ApplicationCommandOption{
+		Type:        discordgo.ApplicationCommandOptionString,
+		Name:        "action",
+		Description: "Which commands the rule applies to",
+		Required:    true,
+		Choices: []*discordgo.ApplicationCommandOptionChoice{
+			{Name: "settings (set default repo/project)", Value: actionSettings},
+			{Name: "close (close issues)", Value: actionClose},
+			{Name: "create (create issues)", Value: actionCreate},
+		},
+	}
+}
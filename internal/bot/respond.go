@@ -0,0 +1,104 @@
+package bot
+
+import (
+	"log"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// deferral records how an interaction was acknowledged by deferResponse or deferUpdate.
+type deferral struct {
+	ephemeral bool
+	// update is set for component interactions, whose response edits the
+	// message the component is attached to.
+	update bool
+}
+
+// deferResponse acknowledges i straight away so the handler can take longer
+// than Discord's 3-second window. The respond* helpers then edit the deferred
+// response instead of creating a new one. ephemeral should match how the
+// handler usually replies on success.
+func (b *Bot) deferResponse(s *discordgo.Session, i *discordgo.InteractionCreate, ephemeral bool) {
+	var flags discordgo.MessageFlags
+	if ephemeral {
+		flags = discordgo.MessageFlagsEphemeral
+	}
+
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredChannelMessageWithSource,
+		Data: &discordgo.InteractionResponseData{Flags: flags},
+	})
+	if err != nil {
+		log.Printf("Failed to defer response: %v", err)
+		return
+	}
+
+	b.deferred.Store(i.ID, deferral{ephemeral: ephemeral})
+}
+
+// deferUpdate acknowledges a component interaction straight away. The next
+// non-ephemeral respond* call replaces the message the component belongs to;
+// ephemeral ones are sent as followups and leave the message alone.
+func (b *Bot) deferUpdate(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+		Type: discordgo.InteractionResponseDeferredMessageUpdate,
+	})
+	if err != nil {
+		log.Printf("Failed to defer update: %v", err)
+		return
+	}
+
+	b.deferred.Store(i.ID, deferral{update: true})
+}
+
+// respond sends data as the reply to i, editing the deferred response if
+// deferResponse or deferUpdate was called. Visibility can't be changed by an
+// edit, so when it differs from the deferred response (typically an ephemeral
+// error after a public deferral) a followup is sent instead and any deferred
+// placeholder is deleted.
+func (b *Bot) respond(s *discordgo.Session, i *discordgo.InteractionCreate, data *discordgo.InteractionResponseData) {
+	value, ok := b.deferred.LoadAndDelete(i.ID)
+	if !ok {
+		err := s.InteractionRespond(i.Interaction, &discordgo.InteractionResponse{
+			Type: discordgo.InteractionResponseChannelMessageWithSource,
+			Data: data,
+		})
+		if err != nil {
+			log.Printf("Failed to respond to interaction: %v", err)
+		}
+		return
+	}
+
+	deferred := value.(deferral)
+	ephemeral := data.Flags&discordgo.MessageFlagsEphemeral != 0
+	if ephemeral == deferred.ephemeral {
+		edit := &discordgo.WebhookEdit{Content: &data.Content}
+		if data.Embeds != nil {
+			edit.Embeds = &data.Embeds
+		}
+		if data.Components != nil {
+			edit.Components = &data.Components
+		}
+
+		if _, err := s.InteractionResponseEdit(i.Interaction, edit); err != nil {
+			log.Printf("Failed to edit deferred response: %v", err)
+		}
+		return
+	}
+
+	if !deferred.update {
+		if err := s.InteractionResponseDelete(i.Interaction); err != nil {
+			log.Printf("Failed to delete deferred response: %v", err)
+		}
+	}
+
+	_, err := s.FollowupMessageCreate(i.Interaction, true, &discordgo.WebhookParams{
+		Content:    data.Content,
+		Embeds:     data.Embeds,
+		Components: data.Components,
+		Flags:      data.Flags,
+	})
+	if err != nil {
+		log.Printf("Failed to send followup message: %v", err)
+	}
+}
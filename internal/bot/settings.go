@@ -0,0 +1,241 @@
+package bot
+
+import (
+	"fmt"
+	"log"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// effectiveSettings are the defaults that apply to a channel after walking
+// thread → channel → category → server → the user's personal defaults, with
+// where each value came from.
+type effectiveSettings struct {
+	DefaultRepo    string
+	RepoSource     string
+	DefaultProject string
+	ProjectSource  string
+}
+
+// settingsScope is one level of the inheritance chain that is stored in channel_settings.
+type settingsScope struct {
+	kind      string
+	channelID string
+	label     string
+}
+
+// getEffectiveSettings resolves the default repo and project for the channel
+// i was sent in, falling back to its parent channel, category, the server and
+// finally the invoking user's personal defaults. Outside a server that has
+// installed the bot only the personal defaults apply.
+func (b *Bot) getEffectiveSettings(s *discordgo.Session, i *discordgo.InteractionCreate) (*effectiveSettings, error) {
+	effective := &effectiveSettings{}
+	apply := func(repo, project, source string) {
+		if effective.DefaultRepo == "" && repo != "" {
+			effective.DefaultRepo = repo
+			effective.RepoSource = source
+		}
+		if effective.DefaultProject == "" && project != "" {
+			effective.DefaultProject = project
+			effective.ProjectSource = source
+		}
+	}
+
+	if guildInstalled(i) {
+		for _, scope := range b.settingsChain(s, i.ChannelID) {
+			settings, err := b.db.GetChannelSettings(scope.channelID)
+			if err != nil {
+				return nil, err
+			}
+			apply(settings.DefaultRepo, settings.DefaultProject, scope.label)
+		}
+
+		settings, err := b.db.GetGuildSettings(i.GuildID)
+		if err != nil {
+			return nil, err
+		}
+		apply(settings.DefaultRepo, settings.DefaultProject, "this server")
+	}
+
+	settings, err := b.db.GetUserSettings(invokingUser(i).ID)
+	if err != nil {
+		return nil, err
+	}
+	apply(settings.DefaultRepo, settings.DefaultProject, "your personal defaults")
+
+	return effective, nil
+}
+
+// settingsChain returns the channel-like scopes that may hold settings for
+// channelID, most specific first. If Discord can't be reached only the channel
+// itself is returned.
+func (b *Bot) settingsChain(s *discordgo.Session, channelID string) []settingsScope {
+	chain := []settingsScope{{kind: "channel", channelID: channelID, label: "this channel"}}
+
+	channel, err := b.lookupChannel(s, channelID)
+	if err != nil {
+		log.Printf("Failed to look up channel %s: %v", channelID, err)
+		return chain
+	}
+
+	if channel.IsThread() && channel.ParentID != "" {
+		parentID := channel.ParentID
+		chain[0].kind = "thread"
+		chain[0].label = "this thread"
+		chain = append(chain, settingsScope{
+			kind:      "channel",
+			channelID: parentID,
+			label:     fmt.Sprintf("parent channel <#%s>", parentID),
+		})
+
+		channel, err = b.lookupChannel(s, parentID)
+		if err != nil {
+			log.Printf("Failed to look up channel %s: %v", parentID, err)
+			return chain
+		}
+	}
+
+	if channel.ParentID != "" {
+		chain = append(chain, settingsScope{
+			kind:      "category",
+			channelID: channel.ParentID,
+			label:     fmt.Sprintf("category <#%s>", channel.ParentID),
+		})
+	}
+
+	return chain
+}
+
+// guildInstalled reports whether i was sent in a server that has installed the
+// bot, as opposed to a DM or a server where only the invoking user installed
+// it. Channel and server settings and permissions only apply in the former.
+func guildInstalled(i *discordgo.InteractionCreate) bool {
+	if i.GuildID == "" {
+		return false
+	}
+
+	// Interactions from before user installs existed don't say who authorized them.
+	if len(i.AuthorizingIntegrationOwners) == 0 {
+		return true
+	}
+
+	_, ok := i.AuthorizingIntegrationOwners[discordgo.ApplicationIntegrationGuildInstall]
+	return ok
+}
+
+func (b *Bot) lookupChannel(s *discordgo.Session, channelID string) (*discordgo.Channel, error) {
+	if channel, err := s.State.Channel(channelID); err == nil {
+		return channel, nil
+	}
+	return s.Channel(channelID)
+}
+
+// saveDefaults applies update to the settings stored for scope ("channel",
+// "category", "server" or "personal") and returns a description of what was
+// changed. Outside a server that has installed the bot, personal defaults are
+// always used.
+func (b *Bot) saveDefaults(s *discordgo.Session, i *discordgo.InteractionCreate, scope string, update func(repo, project *string)) (string, error) {
+	if !guildInstalled(i) {
+		if scope == "channel" || scope == "category" || scope == "server" {
+			return "", fmt.Errorf("%s defaults can only be set in a server that has added the bot", scope)
+		}
+		scope = "personal"
+	}
+
+	switch scope {
+	case "personal":
+		settings, err := b.db.GetUserSettings(invokingUser(i).ID)
+		if err != nil {
+			log.Printf("Failed to get user settings: %v", err)
+			return "", fmt.Errorf("failed to get your settings")
+		}
+
+		update(&settings.DefaultRepo, &settings.DefaultProject)
+
+		if err := b.db.SaveUserSettings(settings); err != nil {
+			log.Printf("Failed to save user settings: %v", err)
+			return "", fmt.Errorf("failed to save your settings")
+		}
+
+		return "your personal defaults", nil
+	case "server":
+		settings, err := b.db.GetGuildSettings(i.GuildID)
+		if err != nil {
+			log.Printf("Failed to get guild settings: %v", err)
+			return "", fmt.Errorf("failed to get server settings")
+		}
+
+		update(&settings.DefaultRepo, &settings.DefaultProject)
+
+		if err := b.db.SaveGuildSettings(settings); err != nil {
+			log.Printf("Failed to save guild settings: %v", err)
+			return "", fmt.Errorf("failed to save server settings")
+		}
+
+		return "this server", nil
+	case "category":
+		target := ""
+		for _, link := range b.settingsChain(s, i.ChannelID) {
+			if link.kind == "category" {
+				target = link.channelID
+			}
+		}
+		if target == "" {
+			return "", fmt.Errorf("this channel is not in a category")
+		}
+
+		if err := b.updateChannelSettings(target, update); err != nil {
+			return "", err
+		}
+
+		return fmt.Sprintf("category <#%s>", target), nil
+	default:
+		if err := b.updateChannelSettings(i.ChannelID, update); err != nil {
+			return "", err
+		}
+
+		return "this channel", nil
+	}
+}
+
+func (b *Bot) updateChannelSettings(channelID string, update func(repo, project *string)) error {
+	settings, err := b.db.GetChannelSettings(channelID)
+	if err != nil {
+		log.Printf("Failed to get channel settings: %v", err)
+		return fmt.Errorf("failed to get channel settings")
+	}
+
+	update(&settings.DefaultRepo, &settings.DefaultProject)
+
+	if err := b.db.SaveChannelSettings(settings); err != nil {
+		log.Printf("Failed to save channel settings: %v", err)
+		return fmt.Errorf("failed to save channel settings")
+	}
+
+	return nil
+}
+
+func (b *Bot) handleSettings(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	settings, err := b.getEffectiveSettings(s, i)
+	if err != nil {
+		log.Printf("Failed to resolve settings: %v", err)
+		b.respondError(s, i, "Failed to get channel settings")
+		return
+	}
+
+	describe := func(value, source string) string {
+		if value == "" {
+			return "_not set_"
+		}
+		return fmt.Sprintf("`%s` (from %s)", value, source)
+	}
+
+	var response strings.Builder
+	response.WriteString("**Effective settings for this channel:**\n\n")
+	response.WriteString(fmt.Sprintf("**Repository:** %s\n", describe(settings.DefaultRepo, settings.RepoSource)))
+	response.WriteString(fmt.Sprintf("**Project:** %s\n", describe(settings.DefaultProject, settings.ProjectSource)))
+	response.WriteString("\nDefaults are inherited from thread → channel → category → server → your personal defaults. Use the `scope` option of `/gh-set-repo` and `/gh-set-project` to change them.")
+
+	b.respondEphemeral(s, i, response.String())
+}
@@ -0,0 +1,227 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"net/url"
+	"path"
+	"regexp"
+	"strconv"
+	"strings"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/google/go-github/v57/github"
+)
+
+const (
+	// maxSnippetsPerMessage caps how many permalinks in one message are expanded.
+	maxSnippetsPerMessage = 2
+
+	// maxMessageLength is Discord's limit on a message's content.
+	maxMessageLength = 2000
+)
+
+// permalinkPath matches the rest of a blob link with a line fragment after the
+// GitHub URL, such as /owner/repo/blob/<sha>/path/to/file.go#L10-L25.
+const permalinkPath = `/([\w.-]+)/([\w.-]+)/blob/([^/\s]+)/([^\s#?<>]+)#L(\d+)(?:C\d+)?(?:-L(\d+)(?:C\d+)?)?`
+
+// languagePattern matches extensions that are safe to use as a code block language.
+var languagePattern = regexp.MustCompile(`^[a-z0-9+#-]+$`)
+
+// snippetLanguages maps file extensions and names to Discord's code block
+// languages where they differ. Other extensions are used as is.
+var snippetLanguages = map[string]string{
+	"py":         "python",
+	"js":         "javascript",
+	"mjs":        "javascript",
+	"cjs":        "javascript",
+	"ts":         "typescript",
+	"rb":         "ruby",
+	"rs":         "rust",
+	"kt":         "kotlin",
+	"kts":        "kotlin",
+	"h":          "c",
+	"cc":         "cpp",
+	"cxx":        "cpp",
+	"hpp":        "cpp",
+	"cs":         "csharp",
+	"sh":         "bash",
+	"zsh":        "bash",
+	"yml":        "yaml",
+	"md":         "markdown",
+	"ex":         "elixir",
+	"exs":        "elixir",
+	"hs":         "haskell",
+	"pl":         "perl",
+	"tf":         "hcl",
+	"vue":        "html",
+	"dockerfile": "dockerfile",
+	"makefile":   "makefile",
+}
+
+// codePermalink is a link to lines of a file at a given ref.
+type codePermalink struct {
+	owner     string
+	repo      string
+	ref       string
+	path      string
+	startLine int
+	endLine   int
+}
+
+// expandPermalinks replies to m with the lines its code permalinks point to,
+// if the channel has previews on. Like issue previews, files are only fetched
+// with the token of the member who turned previews on.
+func (b *Bot) expandPermalinks(s *discordgo.Session, m *discordgo.MessageCreate) {
+	links := b.findPermalinks(m.Content)
+	if len(links) == 0 {
+		return
+	}
+
+	unfurlBy, _, err := b.unfurlSettings(s, m.GuildID, m.ChannelID)
+	if err != nil {
+		log.Printf("Failed to get unfurl settings for channel %s: %v", m.ChannelID, err)
+		return
+	}
+	if unfurlBy == "" {
+		return
+	}
+
+	client, err := b.oauth.GetGitHubClient(unfurlBy)
+	if err != nil {
+		log.Printf("Failed to get GitHub client for unfurling in channel %s: %v", m.ChannelID, err)
+		return
+	}
+
+	ctx := context.Background()
+	for _, link := range links {
+		if !b.allowUnfurl(m.ChannelID) {
+			return
+		}
+
+		file, _, _, err := client.Repositories.GetContents(ctx, link.owner, link.repo, link.path, &github.RepositoryContentGetOptions{Ref: link.ref})
+		if err != nil || file == nil {
+			// Directories, missing files and repositories private to others all end up here.
+			log.Printf("Failed to fetch %s/%s/%s at %s: %v", link.owner, link.repo, link.path, link.ref, err)
+			continue
+		}
+
+		content, err := file.GetContent()
+		if err != nil {
+			log.Printf("Failed to decode %s/%s/%s at %s: %v", link.owner, link.repo, link.path, link.ref, err)
+			continue
+		}
+
+		snippet := formatSnippet(link, content)
+		if snippet == "" {
+			continue
+		}
+
+		_, err = s.ChannelMessageSendComplex(m.ChannelID, &discordgo.MessageSend{
+			Content:         snippet,
+			Reference:       m.Reference(),
+			AllowedMentions: &discordgo.MessageAllowedMentions{},
+		})
+		if err != nil {
+			log.Printf("Failed to send snippet in channel %s: %v", m.ChannelID, err)
+		}
+	}
+}
+
+// findPermalinks returns the code permalinks with line numbers in content, outside code.
+func (b *Bot) findPermalinks(content string) []codePermalink {
+	if !strings.Contains(content, "/blob/") {
+		return nil
+	}
+
+	content = codePattern.ReplaceAllString(content, " ")
+
+	var links []codePermalink
+	for _, match := range b.permalinkPattern.FindAllStringSubmatch(content, -1) {
+		start, _ := strconv.Atoi(match[5])
+		end := start
+		if match[6] != "" {
+			end, _ = strconv.Atoi(match[6])
+		}
+		filePath, err := url.PathUnescape(match[4])
+		if err != nil || start < 1 || end < start {
+			continue
+		}
+
+		links = append(links, codePermalink{
+			owner:     match[1],
+			repo:      match[2],
+			ref:       match[3],
+			path:      filePath,
+			startLine: start,
+			endLine:   end,
+		})
+		if len(links) == maxSnippetsPerMessage {
+			break
+		}
+	}
+
+	return links
+}
+
+// formatSnippet renders the linked lines of content as a code block that fits
+// in a Discord message, dropping trailing lines if needed. It returns "" if
+// the lines don't exist.
+func formatSnippet(link codePermalink, content string) string {
+	lines := strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+	if link.startLine > len(lines) {
+		return ""
+	}
+	end := min(link.endLine, len(lines))
+
+	lineRange := fmt.Sprintf("L%d", link.startLine)
+	if end > link.startLine {
+		lineRange = fmt.Sprintf("L%d-L%d", link.startLine, end)
+	}
+	header := fmt.Sprintf("**%s/%s** `%s` %s at `%.7s`\n", link.owner, link.repo, link.path, lineRange, link.ref)
+	fenceOpen := "```" + snippetLanguage(link.path) + "\n"
+	const fenceClose = "```"
+	const truncated = "\n_…truncated_"
+
+	budget := maxMessageLength - len(header) - len(fenceOpen) - len(fenceClose) - len(truncated)
+
+	var code strings.Builder
+	shown := 0
+	for _, line := range lines[link.startLine-1 : end] {
+		// Keep the code block from being closed early by the file's own backticks.
+		line = strings.ReplaceAll(strings.TrimSuffix(line, "\r"), "```", "`\u200b``")
+		if code.Len()+len(line)+1 > budget {
+			break
+		}
+		code.WriteString(line)
+		code.WriteString("\n")
+		shown++
+	}
+	if shown == 0 {
+		return ""
+	}
+
+	snippet := header + fenceOpen + code.String() + fenceClose
+	if shown < end-link.startLine+1 {
+		snippet += truncated
+	}
+	return snippet
+}
+
+// snippetLanguage infers a code block language from a file's name.
+func snippetLanguage(filePath string) string {
+	name := strings.ToLower(path.Base(filePath))
+	ext := strings.TrimPrefix(path.Ext(name), ".")
+	if ext == "" {
+		ext = name
+	}
+
+	if language, ok := snippetLanguages[ext]; ok {
+		return language
+	}
+	if languagePattern.MatchString(ext) {
+		return ext
+	}
+	return ""
+}
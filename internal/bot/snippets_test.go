@@ -0,0 +1,123 @@
+package bot
+
+import (
+	"fmt"
+	"reflect"
+	"strings"
+	"testing"
+)
+
+func TestFindPermalinks(t *testing.T) {
+	b := newTestBot(t)
+	const sha = "0123456789abcdef0123456789abcdef01234567"
+
+	tests := []struct {
+		name    string
+		content string
+		want    []codePermalink
+	}{
+		{
+			"single line",
+			"https://github.com/owner/repo/blob/" + sha + "/main.go#L10",
+			[]codePermalink{{owner: "owner", repo: "repo", ref: sha, path: "main.go", startLine: 10, endLine: 10}},
+		},
+		{
+			"range with columns and escaped path",
+			"see https://github.com/owner/repo/blob/main/docs/my%20file.md#L3C2-L5C9 here",
+			[]codePermalink{{owner: "owner", repo: "repo", ref: "main", path: "docs/my file.md", startLine: 3, endLine: 5}},
+		},
+		{"reversed range", "https://github.com/owner/repo/blob/main/main.go#L10-L5", nil},
+		{"line zero", "https://github.com/owner/repo/blob/main/main.go#L0", nil},
+		{"no line", "https://github.com/owner/repo/blob/main/main.go", nil},
+		{"inside code", "`https://github.com/owner/repo/blob/main/main.go#L1`", nil},
+		{
+			"capped per message",
+			"https://github.com/o/r/blob/main/a.go#L1 https://github.com/o/r/blob/main/b.go#L2 https://github.com/o/r/blob/main/c.go#L3",
+			[]codePermalink{
+				{owner: "o", repo: "r", ref: "main", path: "a.go", startLine: 1, endLine: 1},
+				{owner: "o", repo: "r", ref: "main", path: "b.go", startLine: 2, endLine: 2},
+			},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := b.findPermalinks(tt.content); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findPermalinks(%q) = %+v, want %+v", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestFormatSnippet(t *testing.T) {
+	link := codePermalink{owner: "owner", repo: "repo", ref: "0123456789abcdef", path: "main.go"}
+	content := "package main\n\nfunc main() {\n\tprintln(\"hi\")\n}\n"
+
+	t.Run("range", func(t *testing.T) {
+		link := link
+		link.startLine, link.endLine = 3, 5
+		want := "**owner/repo** `main.go` L3-L5 at `0123456`\n```go\nfunc main() {\n\tprintln(\"hi\")\n}\n```"
+		if got := formatSnippet(link, content); got != want {
+			t.Errorf("formatSnippet() = %q, want %q", got, want)
+		}
+	})
+
+	t.Run("range past end of file is clipped", func(t *testing.T) {
+		link := link
+		link.startLine, link.endLine = 5, 50
+		if got := formatSnippet(link, content); !strings.Contains(got, " L5 at ") || !strings.HasSuffix(got, "}\n```") {
+			t.Errorf("formatSnippet() = %q, want just line 5", got)
+		}
+	})
+
+	t.Run("start past end of file", func(t *testing.T) {
+		link := link
+		link.startLine, link.endLine = 6, 8
+		if got := formatSnippet(link, content); got != "" {
+			t.Errorf("formatSnippet() = %q, want empty", got)
+		}
+	})
+
+	t.Run("backticks in the file", func(t *testing.T) {
+		link := link
+		link.path = "README.md"
+		link.startLine, link.endLine = 1, 3
+		got := formatSnippet(link, "```go\nx := 1\n```\n")
+		body := strings.TrimSuffix(strings.SplitN(got, "```markdown\n", 2)[1], "```")
+		if strings.Contains(body, "```") {
+			t.Errorf("formatSnippet() = %q, want the file's fences broken up", got)
+		}
+	})
+
+	t.Run("long range is truncated", func(t *testing.T) {
+		var long strings.Builder
+		for n := 1; n <= 500; n++ {
+			fmt.Fprintf(&long, "line %d of a long file that goes on and on\n", n)
+		}
+		link := link
+		link.startLine, link.endLine = 1, 500
+		got := formatSnippet(link, long.String())
+		if len(got) > maxMessageLength {
+			t.Errorf("formatSnippet() is %d bytes, want at most %d", len(got), maxMessageLength)
+		}
+		if !strings.HasSuffix(got, "```\n_…truncated_") || !strings.Contains(got, "line 1 of") {
+			t.Errorf("formatSnippet() = %q, want the first lines and a truncation note", got)
+		}
+	})
+}
+
+func TestSnippetLanguage(t *testing.T) {
+	tests := map[string]string{
+		"main.go":          "go",
+		"src/app.ts":       "typescript",
+		"build/Dockerfile": "dockerfile",
+		"Makefile":         "makefile",
+		"weird.c++~":       "",
+	}
+
+	for file, want := range tests {
+		if got := snippetLanguage(file); got != want {
+			t.Errorf("snippetLanguage(%q) = %q, want %q", file, got, want)
+		}
+	}
+}
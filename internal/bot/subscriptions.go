@@ -0,0 +1,257 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"path"
+	"strings"
+
+	"discord-github-bot/internal/database"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+// subscriptionFilterOptions are the filter options of /gh-subscribe.
+func subscriptionFilterOptions() []*discordgo.ApplicationCommandOption {
+	return []*discordgo.ApplicationCommandOption{
+		{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "events",
+			Description: "Comma-separated: " + strings.Join(subscriptionEvents, ", ") + " (default: all)",
+			Required:    false,
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "labels",
+			Description: "Only issues and pull requests with one of these comma-separated labels",
+			Required:    false,
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "branches",
+			Description: "Only pushes, pull requests and workflow runs on these branches, e.g. main, release/*",
+			Required:    false,
+		},
+		{
+			Type:        discordgo.ApplicationCommandOptionString,
+			Name:        "paths",
+			Description: "Only pushes touching these files or directories, e.g. web/, docs/*.md, *.go (any directory)",
+			Required:    false,
+		},
+	}
+}
+
+func (b *Bot) handleSubscribe(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if !guildInstalled(i) {
+		b.respondError(s, i, "This command can only be used in a server")
+		return
+	}
+
+	options := i.ApplicationCommandData().Options
+	repo := b.getStringOption(options, "repo")
+	if !strings.Contains(repo, "/") {
+		b.respondError(s, i, "Repository must be in format: owner/repo")
+		return
+	}
+
+	events, err := parseEvents(b.getStringOption(options, "events"))
+	if err != nil {
+		b.respondError(s, i, err.Error())
+		return
+	}
+
+	branches := splitOption(b.getStringOption(options, "branches"))
+	paths := splitOption(b.getStringOption(options, "paths"))
+	for _, patterns := range [][]string{branches, paths} {
+		for _, pattern := range patterns {
+			if _, err := path.Match(pattern, ""); err != nil {
+				b.respondError(s, i, fmt.Sprintf("Invalid pattern `%s`", pattern))
+				return
+			}
+		}
+	}
+
+	b.deferResponse(s, i, false)
+
+	// The webhook may cover private repositories, so only members who can read
+	// the repository themselves may have its events posted.
+	client, err := b.oauth.GetGitHubClient(invokingUser(i).ID)
+	if err != nil {
+		b.respondError(s, i, "You must authenticate first. Use /gh-auth")
+		return
+	}
+	owner, repoName, _ := strings.Cut(repo, "/")
+	if _, _, err := client.Repositories.Get(context.Background(), owner, repoName); err != nil {
+		log.Printf("Failed to get repository %s for subscription: %v", repo, err)
+		b.respondError(s, i, fmt.Sprintf("Your GitHub account can't access %s", repo))
+		return
+	}
+
+	sub := &database.Subscription{
+		GuildID:   i.GuildID,
+		ChannelID: i.ChannelID,
+		Repo:      repo,
+		CreatedBy: invokingUser(i).ID,
+		Events:    events,
+		Labels:    splitOption(b.getStringOption(options, "labels")),
+		Paths:     paths,
+		Branches:  branches,
+	}
+
+	err = b.db.SaveSubscription(sub)
+	b.audit(i, &database.AuditEntry{Repo: sub.Repo, Details: describeSubscription(sub)}, err)
+	if err != nil {
+		log.Printf("Failed to save subscription: %v", err)
+		b.respondError(s, i, "Failed to save subscription")
+		return
+	}
+
+	message := fmt.Sprintf("✅ This channel is now subscribed to **%s**: %s", sub.Repo, describeSubscription(sub))
+	if b.config.GitHubWebhookSecret == "" || !b.config.OAuthServerEnabled {
+		message += "\n\n⚠️ Webhooks are not enabled on this bot, so no events will be posted until its operator sets them up."
+	}
+
+	b.respondSuccess(s, i, message)
+}
+
+// handleUnsubscribe removes a channel's subscription to a repository or, when
+// events are given, just those events from it.
+func (b *Bot) handleUnsubscribe(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if !guildInstalled(i) {
+		b.respondError(s, i, "This command can only be used in a server")
+		return
+	}
+
+	options := i.ApplicationCommandData().Options
+	repo := b.getStringOption(options, "repo")
+
+	events, err := parseEvents(b.getStringOption(options, "events"))
+	if err != nil {
+		b.respondError(s, i, err.Error())
+		return
+	}
+
+	sub, err := b.db.GetSubscription(i.ChannelID, repo)
+	if err != nil {
+		log.Printf("Failed to get subscription: %v", err)
+		b.respondError(s, i, "Failed to get subscription")
+		return
+	}
+	if sub == nil {
+		b.respondError(s, i, fmt.Sprintf("This channel is not subscribed to %s", repo))
+		return
+	}
+
+	remaining := sub.Events
+	if len(remaining) == 0 {
+		remaining = subscriptionEvents
+	}
+	var kept []string
+	for _, event := range remaining {
+		if len(events) > 0 && !containsFold(events, event) {
+			kept = append(kept, event)
+		}
+	}
+
+	if len(kept) > 0 {
+		sub.Events = kept
+		err = b.db.SaveSubscription(sub)
+		b.audit(i, &database.AuditEntry{Repo: sub.Repo, Details: describeSubscription(sub)}, err)
+		if err != nil {
+			log.Printf("Failed to save subscription: %v", err)
+			b.respondError(s, i, "Failed to update subscription")
+			return
+		}
+
+		b.respondSuccess(s, i, fmt.Sprintf("✅ This channel's subscription to **%s** now covers: %s", sub.Repo, describeSubscription(sub)))
+		return
+	}
+
+	_, err = b.db.DeleteSubscription(i.ChannelID, sub.Repo)
+	b.audit(i, &database.AuditEntry{Repo: sub.Repo, Details: "unsubscribed"}, err)
+	if err != nil {
+		log.Printf("Failed to delete subscription: %v", err)
+		b.respondError(s, i, "Failed to remove subscription")
+		return
+	}
+
+	b.respondSuccess(s, i, fmt.Sprintf("✅ This channel is no longer subscribed to **%s**", sub.Repo))
+}
+
+func (b *Bot) handleSubscriptions(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if !guildInstalled(i) {
+		b.respondError(s, i, "This command can only be used in a server")
+		return
+	}
+
+	subs, err := b.db.ListSubscriptionsByChannel(i.ChannelID)
+	if err != nil {
+		log.Printf("Failed to list subscriptions: %v", err)
+		b.respondError(s, i, "Failed to list subscriptions")
+		return
+	}
+
+	if len(subs) == 0 {
+		b.respondEphemeral(s, i, "This channel has no subscriptions. Use `/gh-subscribe` to post GitHub events here.")
+		return
+	}
+
+	var response strings.Builder
+	response.WriteString("**GitHub events posted to this channel:**\n\n")
+	for _, sub := range subs {
+		response.WriteString(fmt.Sprintf("**%s:** %s\n", sub.Repo, describeSubscription(sub)))
+	}
+
+	b.respondEphemeral(s, i, truncate(response.String(), 2000))
+}
+
+// describeSubscription summarizes which events sub lets through.
+func describeSubscription(sub *database.Subscription) string {
+	events := "all events"
+	if len(sub.Events) > 0 {
+		events = strings.Join(sub.Events, ", ")
+	}
+
+	var filters []string
+	if len(sub.Labels) > 0 {
+		filters = append(filters, "labels "+formatList(sub.Labels))
+	}
+	if len(sub.Branches) > 0 {
+		filters = append(filters, "branches "+formatList(sub.Branches))
+	}
+	if len(sub.Paths) > 0 {
+		filters = append(filters, "paths "+formatList(sub.Paths))
+	}
+
+	if len(filters) == 0 {
+		return events
+	}
+	return fmt.Sprintf("%s (%s)", events, strings.Join(filters, "; "))
+}
+
+func formatList(values []string) string {
+	return "`" + strings.Join(values, "`, `") + "`"
+}
+
+// parseEvents parses the events option, returning nil for all events.
+func parseEvents(value string) ([]string, error) {
+	events := splitOption(strings.ToLower(value))
+	for _, event := range events {
+		if !containsFold(subscriptionEvents, event) {
+			return nil, fmt.Errorf("unknown event %q (available: %s)", event, strings.Join(subscriptionEvents, ", "))
+		}
+	}
+	return events, nil
+}
+
+// splitOption splits a comma-separated option value, dropping empty entries.
+func splitOption(value string) []string {
+	var values []string
+	for _, v := range strings.Split(value, ",") {
+		if v = strings.TrimSpace(v); v != "" {
+			values = append(values, v)
+		}
+	}
+	return values
+}
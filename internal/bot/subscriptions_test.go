@@ -0,0 +1,48 @@
+package bot
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestParseEvents(t *testing.T) {
+	tests := []struct {
+		value   string
+		want    []string
+		wantErr bool
+	}{
+		{"", nil, false},
+		{"issues", []string{"issues"}, false},
+		{" Issues, PUSH ,", []string{"issues", "push"}, false},
+		{"issues, deployments", nil, true},
+	}
+
+	for _, tt := range tests {
+		got, err := parseEvents(tt.value)
+		if (err != nil) != tt.wantErr {
+			t.Errorf("parseEvents(%q) error = %v, want error %v", tt.value, err, tt.wantErr)
+			continue
+		}
+		if !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("parseEvents(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
+
+func TestSplitOption(t *testing.T) {
+	tests := []struct {
+		value string
+		want  []string
+	}{
+		{"", nil},
+		{" , ,", nil},
+		{"bug", []string{"bug"}},
+		{"bug, good first issue ,docs", []string{"bug", "good first issue", "docs"}},
+	}
+
+	for _, tt := range tests {
+		if got := splitOption(tt.value); !reflect.DeepEqual(got, tt.want) {
+			t.Errorf("splitOption(%q) = %q, want %q", tt.value, got, tt.want)
+		}
+	}
+}
@@ -0,0 +1,319 @@
+package bot
+
+import (
+	"context"
+	"fmt"
+	"log"
+	"regexp"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"discord-github-bot/internal/database"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/google/go-github/v57/github"
+)
+
+const (
+	// maxUnfurlsPerMessage caps how many references in one message are previewed.
+	maxUnfurlsPerMessage = 3
+
+	// maxUnfurlsPerWindow is how many previews a channel gets per unfurlWindow,
+	// so pasting a changelog doesn't flood it.
+	maxUnfurlsPerWindow = 5
+	unfurlWindow        = time.Minute
+)
+
+var (
+	// repoRefPattern matches owner/repo#123.
+	repoRefPattern = regexp.MustCompile(`(?:^|[^\w./-])([\w.-]+)/([\w.-]+)#(\d+)\b`)
+
+	// bareRefPattern matches #123, but not channel mentions like <#123> or URL fragments.
+	bareRefPattern = regexp.MustCompile(`(?:^|[^\w/&<#])#(\d+)\b`)
+
+	// codePattern matches code blocks and inline code, where references are left alone.
+	codePattern = regexp.MustCompile("(?s)```.*?```|`[^`\n]*`")
+)
+
+// issueRef is an issue or pull request mentioned in a message. Bare #123
+// references have no owner or repo until resolved against the channel's default.
+type issueRef struct {
+	owner  string
+	repo   string
+	number int
+}
+
+func (r issueRef) String() string {
+	return fmt.Sprintf("%s/%s#%d", r.owner, r.repo, r.number)
+}
+
+type rateWindow struct {
+	mu    sync.Mutex
+	start time.Time
+	count int
+}
+
+func (b *Bot) handleUnfurl(s *discordgo.Session, i *discordgo.InteractionCreate) {
+	if !guildInstalled(i) {
+		b.respondError(s, i, "This command can only be used in a server")
+		return
+	}
+
+	enabled := false
+	for _, opt := range i.ApplicationCommandData().Options {
+		if opt.Name == "enabled" {
+			enabled = opt.BoolValue()
+		}
+	}
+
+	userID := invokingUser(i).ID
+	if enabled {
+		user, err := b.db.GetUser(userID)
+		if err != nil || user == nil {
+			b.respondError(s, i, "Previews are fetched with your GitHub account. Use /gh-auth first.")
+			return
+		}
+	}
+
+	settings, err := b.db.GetChannelSettings(i.ChannelID)
+	if err == nil {
+		// Turning previews off is stored explicitly, so that it overrides a parent
+		// channel or category that has them on.
+		settings.UnfurlBy = ""
+		settings.UnfurlOff = !enabled
+		if enabled {
+			settings.UnfurlBy = userID
+		}
+		err = b.db.SaveChannelSettings(settings)
+	}
+	b.audit(i, &database.AuditEntry{Details: fmt.Sprintf("issue previews: %t", enabled)}, err)
+	if err != nil {
+		log.Printf("Failed to save unfurl setting: %v", err)
+		b.respondError(s, i, "Failed to save channel settings")
+		return
+	}
+
+	if !enabled {
+		b.respondSuccess(s, i, "✅ Issue and pull request references and code permalinks in this channel will no longer be previewed.")
+		return
+	}
+
+	message := "✅ Issue and pull request references and code permalinks in this channel will be previewed using your GitHub account, so anyone here can see titles and code from repositories you can access. Bare `#123` references use the channel's default repository."
+	if !b.config.DiscordMessageContent {
+		message += "\n\n⚠️ The bot can't read message content, so nothing will be previewed until its operator enables the Message Content intent."
+	}
+
+	b.respondSuccess(s, i, message)
+}
+
+// unfurlReferences replies to m with previews of the issues and pull requests
+// it mentions, if the channel has previews on. Only the token of the member
+// who turned previews on is used, so private repositories are only shown
+// where that member chose to show them.
+func (b *Bot) unfurlReferences(s *discordgo.Session, m *discordgo.MessageCreate) {
+	refs := b.findIssueRefs(m.Content)
+	if len(refs) == 0 {
+		return
+	}
+
+	unfurlBy, defaultRepo, err := b.unfurlSettings(s, m.GuildID, m.ChannelID)
+	if err != nil {
+		log.Printf("Failed to get unfurl settings for channel %s: %v", m.ChannelID, err)
+		return
+	}
+	if unfurlBy == "" {
+		return
+	}
+
+	var resolved []issueRef
+	seen := make(map[string]bool)
+	for _, ref := range refs {
+		if ref.owner == "" {
+			owner, repo, found := strings.Cut(defaultRepo, "/")
+			if !found {
+				continue
+			}
+			ref.owner, ref.repo = owner, repo
+		}
+
+		key := strings.ToLower(ref.String())
+		if seen[key] {
+			continue
+		}
+		seen[key] = true
+
+		resolved = append(resolved, ref)
+		if len(resolved) == maxUnfurlsPerMessage {
+			break
+		}
+	}
+	if len(resolved) == 0 || !b.allowUnfurl(m.ChannelID) {
+		return
+	}
+
+	client, err := b.oauth.GetGitHubClient(unfurlBy)
+	if err != nil {
+		log.Printf("Failed to get GitHub client for unfurling in channel %s: %v", m.ChannelID, err)
+		return
+	}
+
+	ctx := context.Background()
+	var embeds []*discordgo.MessageEmbed
+	for _, ref := range resolved {
+		issue, _, err := client.Issues.Get(ctx, ref.owner, ref.repo, ref.number)
+		if err != nil {
+			// Most often the reference isn't an issue, or the repository is private to others.
+			log.Printf("Failed to unfurl %s: %v", ref, err)
+			continue
+		}
+
+		merged := false
+		if issue.IsPullRequest() && issue.GetState() == "closed" {
+			if pr, _, err := client.PullRequests.Get(ctx, ref.owner, ref.repo, ref.number); err == nil {
+				merged = pr.GetMerged()
+			}
+		}
+
+		embeds = append(embeds, unfurlEmbed(ref, issue, merged))
+	}
+	if len(embeds) == 0 {
+		return
+	}
+
+	_, err = s.ChannelMessageSendComplex(m.ChannelID, &discordgo.MessageSend{
+		Embeds:          embeds,
+		Reference:       m.Reference(),
+		AllowedMentions: &discordgo.MessageAllowedMentions{},
+	})
+	if err != nil {
+		log.Printf("Failed to send previews in channel %s: %v", m.ChannelID, err)
+	}
+}
+
+// findIssueRefs returns the references in content, outside code, in the order
+// URLs, owner/repo#123 and #123.
+func (b *Bot) findIssueRefs(content string) []issueRef {
+	if !strings.Contains(content, "#") && !strings.Contains(content, "/") {
+		return nil
+	}
+
+	content = codePattern.ReplaceAllString(content, " ")
+
+	var refs []issueRef
+	for _, match := range b.issueURLPattern.FindAllStringSubmatch(content, -1) {
+		number, _ := strconv.Atoi(match[3])
+		refs = append(refs, issueRef{owner: match[1], repo: match[2], number: number})
+	}
+	// Fragments such as #issuecomment-123 must not be read as bare references.
+	content = b.issueURLPattern.ReplaceAllString(content, " ")
+
+	for _, match := range repoRefPattern.FindAllStringSubmatch(content, -1) {
+		number, _ := strconv.Atoi(match[3])
+		refs = append(refs, issueRef{owner: match[1], repo: match[2], number: number})
+	}
+
+	for _, match := range bareRefPattern.FindAllStringSubmatch(content, -1) {
+		number, _ := strconv.Atoi(match[1])
+		refs = append(refs, issueRef{number: number})
+	}
+
+	return refs
+}
+
+// unfurlSettings returns who previews references in channelID and the default
+// repository for bare references, from the channel, its parent channel or
+// category, and the server. The most specific scope that turns previews on or
+// off decides whether they are shown.
+func (b *Bot) unfurlSettings(s *discordgo.Session, guildID, channelID string) (unfurlBy, defaultRepo string, err error) {
+	decided := false
+	for _, scope := range b.settingsChain(s, channelID) {
+		settings, err := b.db.GetChannelSettings(scope.channelID)
+		if err != nil {
+			return "", "", err
+		}
+		if !decided && (settings.UnfurlBy != "" || settings.UnfurlOff) {
+			unfurlBy = settings.UnfurlBy
+			decided = true
+		}
+		if defaultRepo == "" {
+			defaultRepo = settings.DefaultRepo
+		}
+	}
+	if unfurlBy == "" || defaultRepo != "" {
+		return unfurlBy, defaultRepo, nil
+	}
+
+	settings, err := b.db.GetGuildSettings(guildID)
+	if err != nil {
+		return "", "", err
+	}
+	return unfurlBy, settings.DefaultRepo, nil
+}
+
+// allowUnfurl reports whether channelID may get another preview in the
+// current rate limit window, counting it if so.
+func (b *Bot) allowUnfurl(channelID string) bool {
+	value, _ := b.unfurlWindows.LoadOrStore(channelID, &rateWindow{})
+	window := value.(*rateWindow)
+
+	window.mu.Lock()
+	defer window.mu.Unlock()
+
+	now := time.Now()
+	if now.Sub(window.start) >= unfurlWindow {
+		window.start = now
+		window.count = 0
+	}
+	if window.count >= maxUnfurlsPerWindow {
+		return false
+	}
+	window.count++
+	return true
+}
+
+// unfurlEmbed is a compact preview of issue: state, title, labels and assignees.
+func unfurlEmbed(ref issueRef, issue *github.Issue, merged bool) *discordgo.MessageEmbed {
+	kind := "Issue"
+	if issue.IsPullRequest() {
+		kind = "Pull request"
+	}
+
+	state, color := "open", colorOpened
+	switch {
+	case merged:
+		state, color = "merged", colorMerged
+	case issue.GetState() == "closed":
+		state, color = "closed", colorClosed
+	}
+
+	embed := &discordgo.MessageEmbed{
+		Title:       truncate(fmt.Sprintf("%s/%s#%d %s", ref.owner, ref.repo, ref.number, issue.GetTitle()), 255),
+		URL:         issue.GetHTMLURL(),
+		Color:       color,
+		Description: fmt.Sprintf("%s %s by **%s**", kind, state, issue.GetUser().GetLogin()),
+	}
+
+	if len(issue.Labels) > 0 {
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   "Labels",
+			Value:  truncate(strings.Join(labelNames(issue.Labels), ", "), 1024),
+			Inline: true,
+		})
+	}
+
+	if len(issue.Assignees) > 0 {
+		assignees := make([]string, 0, len(issue.Assignees))
+		for _, assignee := range issue.Assignees {
+			assignees = append(assignees, assignee.GetLogin())
+		}
+		embed.Fields = append(embed.Fields, &discordgo.MessageEmbedField{
+			Name:   "Assignees",
+			Value:  truncate(strings.Join(assignees, ", "), 1024),
+			Inline: true,
+		})
+	}
+
+	return embed
+}
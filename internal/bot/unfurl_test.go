@@ -0,0 +1,112 @@
+package bot
+
+import (
+	"reflect"
+	"testing"
+
+	"discord-github-bot/internal/database"
+
+	"github.com/bwmarrin/discordgo"
+)
+
+func TestFindIssueRefs(t *testing.T) {
+	b := newTestBot(t)
+
+	tests := []struct {
+		name    string
+		content string
+		want    []issueRef
+	}{
+		{"bare", "Fixed in #12.", []issueRef{{number: 12}}},
+		{"bare in parentheses", "Duplicate (#8)", []issueRef{{number: 8}}},
+		{"owner/repo", "See owner/repo#3", []issueRef{{owner: "owner", repo: "repo", number: 3}}},
+		{"issue URL", "https://github.com/owner/repo/issues/5", []issueRef{{owner: "owner", repo: "repo", number: 5}}},
+		{"pull request URL with fragment", "https://github.com/owner/repo/pull/7#issuecomment-99", []issueRef{{owner: "owner", repo: "repo", number: 7}}},
+		{
+			"in order URLs, owner/repo, bare",
+			"#1 and a/b#2 and https://github.com/c/d/issues/3",
+			[]issueRef{{owner: "c", repo: "d", number: 3}, {owner: "a", repo: "b", number: 2}, {number: 1}},
+		},
+		{"channel mention", "Ask in <#123456789>", nil},
+		{"HTML entity", "Escaped &#123; brace", nil},
+		{"URL fragment", "https://example.com/changelog#42", nil},
+		{"word with hash", "C#1 and issue#2", nil},
+		{"other host", "https://gitlab.com/owner/repo/issues/5", nil},
+		{"inline code", "Run `git log #4`", nil},
+		{"code block", "```\nlet x = #5\n```", nil},
+		{"nothing", "No references here", nil},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := b.findIssueRefs(tt.content); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("findIssueRefs(%q) = %v, want %v", tt.content, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestUnfurlSettingsInheritance(t *testing.T) {
+	b := newTestBot(t)
+	addChannels(t, b, "g1",
+		&discordgo.Channel{ID: "category", Type: discordgo.ChannelTypeGuildCategory},
+		&discordgo.Channel{ID: "general", Type: discordgo.ChannelTypeGuildText, ParentID: "category"},
+		&discordgo.Channel{ID: "quiet", Type: discordgo.ChannelTypeGuildPublicThread, ParentID: "general"},
+		&discordgo.Channel{ID: "busy", Type: discordgo.ChannelTypeGuildPublicThread, ParentID: "general"},
+		&discordgo.Channel{ID: "offtopic", Type: discordgo.ChannelTypeGuildText, ParentID: "category"},
+	)
+
+	for _, settings := range []*database.ChannelSettings{
+		{ChannelID: "category", UnfurlBy: "u1"},
+		{ChannelID: "general", UnfurlBy: "u2", DefaultRepo: "owner/general"},
+		{ChannelID: "quiet", UnfurlOff: true},
+		{ChannelID: "offtopic", UnfurlOff: true},
+	} {
+		if err := b.db.SaveChannelSettings(settings); err != nil {
+			t.Fatalf("SaveChannelSettings: %v", err)
+		}
+	}
+	if err := b.db.SaveGuildSettings(&database.GuildSettings{GuildID: "g1", DefaultRepo: "owner/server"}); err != nil {
+		t.Fatalf("SaveGuildSettings: %v", err)
+	}
+
+	tests := []struct {
+		channelID       string
+		wantUnfurlBy    string
+		wantDefaultRepo string
+	}{
+		{"category", "u1", "owner/server"},
+		{"general", "u2", "owner/general"},
+		{"busy", "u2", "owner/general"},
+		// Turned off in the thread even though its channel has previews on.
+		{"quiet", "", "owner/general"},
+		// With previews off, the server's default repository isn't looked up.
+		{"offtopic", "", ""},
+	}
+
+	for _, tt := range tests {
+		unfurlBy, defaultRepo, err := b.unfurlSettings(b.session, "g1", tt.channelID)
+		if err != nil {
+			t.Fatalf("unfurlSettings(%s): %v", tt.channelID, err)
+		}
+		if unfurlBy != tt.wantUnfurlBy || defaultRepo != tt.wantDefaultRepo {
+			t.Errorf("unfurlSettings(%s) = %q, %q; want %q, %q", tt.channelID, unfurlBy, defaultRepo, tt.wantUnfurlBy, tt.wantDefaultRepo)
+		}
+	}
+}
+
+func TestAllowUnfurl(t *testing.T) {
+	b := newTestBot(t)
+
+	for n := 0; n < maxUnfurlsPerWindow; n++ {
+		if !b.allowUnfurl("c1") {
+			t.Fatalf("allowUnfurl refused preview %d of %d", n+1, maxUnfurlsPerWindow)
+		}
+	}
+	if b.allowUnfurl("c1") {
+		t.Fatal("allowUnfurl allowed more than maxUnfurlsPerWindow previews")
+	}
+	if !b.allowUnfurl("c2") {
+		t.Fatal("allowUnfurl limited another channel")
+	}
+}
@@ -0,0 +1,351 @@
+package bot
+
+import (
+	"errors"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+
+	"discord-github-bot/internal/database"
+	"discord-github-bot/internal/webhook"
+
+	"github.com/bwmarrin/discordgo"
+	"github.com/google/go-github/v57/github"
+)
+
+// Embed colors for webhook events.
+const (
+	colorOpened  = 0x2da44e
+	colorClosed  = 0xcf222e
+	colorMerged  = 0x8250df
+	colorNeutral = 0x57606a
+
+	// maxCommitsShown limits how many commits a push embed lists.
+	maxCommitsShown = 5
+
+	// maxEventBodyLength limits how much of an issue, PR or release body is quoted.
+	maxEventBodyLength = 300
+)
+
+// Event kinds channels can subscribe to, named after their X-GitHub-Event header.
+const (
+	eventIssues      = "issues"
+	eventPullRequest = "pull_request"
+	eventPush        = "push"
+	eventRelease     = "release"
+	eventWorkflowRun = "workflow_run"
+)
+
+var subscriptionEvents = []string{eventIssues, eventPullRequest, eventPush, eventRelease, eventWorkflowRun}
+
+// githubEvent is a webhook event reduced to what is needed to route and post it.
+type githubEvent struct {
+	kind  string
+	repo  string
+	embed *discordgo.MessageEmbed
+
+	// labels are the issue or pull request labels.
+	labels []string
+	// branch is the pushed branch, pull request base branch or workflow run head branch.
+	branch string
+	// paths are the files changed by a push.
+	paths []string
+}
+
+// HandleGitHubEvent mirrors event into linked forum posts and posts it to every
+// channel subscribed to its repository. It implements webhook.EventHandler.
+// Failures don't stop the remaining channels from getting the event, but are
+// returned so a redelivery can retry them.
+func (b *Bot) HandleGitHubEvent(delivery *webhook.Delivery, eventType string, event interface{}) error {
+	var errs []error
+	if err := delivery.Deliver("forum", func() error { return b.syncIssueEvent(event) }); err != nil {
+		errs = append(errs, fmt.Errorf("failed to sync %s event to a forum post: %w", eventType, err))
+	}
+
+	ev := newGitHubEvent(event)
+	if ev == nil {
+		return errors.Join(errs...)
+	}
+
+	subs, err := b.db.ListSubscriptionsByRepo(ev.repo)
+	if err != nil {
+		return errors.Join(append(errs, err)...)
+	}
+
+	for _, sub := range subs {
+		if !ev.matches(sub) {
+			continue
+		}
+		err := delivery.Deliver("channel:"+sub.ChannelID, func() error {
+			_, err := b.session.ChannelMessageSendEmbed(sub.ChannelID, ev.embed)
+			return err
+		})
+		if err != nil {
+			errs = append(errs, fmt.Errorf("failed to post %s event for %s to channel %s: %w", ev.kind, ev.repo, sub.ChannelID, err))
+		}
+	}
+
+	return errors.Join(errs...)
+}
+
+// newGitHubEvent builds the embed for event, or returns nil for events and
+// actions that aren't posted.
+func newGitHubEvent(event interface{}) *githubEvent {
+	switch e := event.(type) {
+	case *github.IssuesEvent:
+		return issuesEvent(e)
+	case *github.PullRequestEvent:
+		return pullRequestEvent(e)
+	case *github.PushEvent:
+		return pushEvent(e)
+	case *github.ReleaseEvent:
+		return releaseEvent(e)
+	case *github.WorkflowRunEvent:
+		return workflowRunEvent(e)
+	}
+	return nil
+}
+
+func issuesEvent(e *github.IssuesEvent) *githubEvent {
+	issue := e.GetIssue()
+	repo := e.GetRepo().GetFullName()
+
+	var color int
+	switch e.GetAction() {
+	case "opened", "reopened":
+		color = colorOpened
+	case "closed":
+		color = colorClosed
+	default:
+		return nil
+	}
+
+	embed := eventEmbed(e.GetSender(), color)
+	embed.Title = truncate(fmt.Sprintf("[%s] Issue %s: #%d %s", repo, e.GetAction(), issue.GetNumber(), issue.GetTitle()), 255)
+	embed.URL = issue.GetHTMLURL()
+	if e.GetAction() == "opened" {
+		embed.Description = truncate(issue.GetBody(), maxEventBodyLength)
+	}
+
+	return &githubEvent{kind: eventIssues, repo: repo, embed: embed, labels: labelNames(issue.Labels)}
+}
+
+func pullRequestEvent(e *github.PullRequestEvent) *githubEvent {
+	pr := e.GetPullRequest()
+	repo := e.GetRepo().GetFullName()
+
+	action := e.GetAction()
+	var color int
+	switch action {
+	case "opened", "reopened":
+		color = colorOpened
+	case "ready_for_review":
+		action = "ready for review"
+		color = colorOpened
+	case "closed":
+		color = colorClosed
+		if pr.GetMerged() {
+			action = "merged"
+			color = colorMerged
+		}
+	default:
+		return nil
+	}
+
+	embed := eventEmbed(e.GetSender(), color)
+	embed.Title = truncate(fmt.Sprintf("[%s] Pull request %s: #%d %s", repo, action, pr.GetNumber(), pr.GetTitle()), 255)
+	embed.URL = pr.GetHTMLURL()
+	if e.GetAction() == "opened" {
+		embed.Description = truncate(pr.GetBody(), maxEventBodyLength)
+	}
+	embed.Fields = []*discordgo.MessageEmbedField{
+		{Name: "Branch", Value: fmt.Sprintf("`%s` → `%s`", pr.GetHead().GetRef(), pr.GetBase().GetRef()), Inline: true},
+	}
+
+	return &githubEvent{kind: eventPullRequest, repo: repo, embed: embed, labels: labelNames(pr.Labels), branch: pr.GetBase().GetRef()}
+}
+
+func pushEvent(e *github.PushEvent) *githubEvent {
+	if e.GetDeleted() || len(e.Commits) == 0 {
+		return nil
+	}
+
+	repo := e.GetRepo().GetFullName()
+	branch := strings.TrimPrefix(e.GetRef(), "refs/heads/")
+
+	noun := "commits"
+	if len(e.Commits) == 1 {
+		noun = "commit"
+	}
+
+	var paths []string
+	for _, commit := range e.Commits {
+		paths = append(paths, commit.Added...)
+		paths = append(paths, commit.Removed...)
+		paths = append(paths, commit.Modified...)
+	}
+
+	var description strings.Builder
+	for n, commit := range e.Commits {
+		if n == maxCommitsShown {
+			description.WriteString(fmt.Sprintf("…and %d more\n", len(e.Commits)-n))
+			break
+		}
+		message, _, _ := strings.Cut(commit.GetMessage(), "\n")
+		description.WriteString(fmt.Sprintf("[`%.7s`](%s) %s — %s\n",
+			commit.GetID(), commit.GetURL(), truncate(message, 80), commit.GetAuthor().GetName()))
+	}
+
+	embed := eventEmbed(e.GetSender(), colorNeutral)
+	embed.Title = truncate(fmt.Sprintf("[%s:%s] %d new %s", repo, branch, len(e.Commits), noun), 255)
+	embed.URL = e.GetCompare()
+	embed.Description = description.String()
+
+	return &githubEvent{kind: eventPush, repo: repo, embed: embed, branch: branch, paths: paths}
+}
+
+func releaseEvent(e *github.ReleaseEvent) *githubEvent {
+	if e.GetAction() != "published" {
+		return nil
+	}
+
+	release := e.GetRelease()
+	repo := e.GetRepo().GetFullName()
+
+	name := release.GetName()
+	if name == "" {
+		name = release.GetTagName()
+	}
+
+	embed := eventEmbed(e.GetSender(), colorOpened)
+	embed.Title = truncate(fmt.Sprintf("[%s] Release published: %s", repo, name), 255)
+	embed.URL = release.GetHTMLURL()
+	embed.Description = truncate(release.GetBody(), maxEventBodyLength)
+
+	return &githubEvent{kind: eventRelease, repo: repo, embed: embed}
+}
+
+func workflowRunEvent(e *github.WorkflowRunEvent) *githubEvent {
+	if e.GetAction() != "completed" {
+		return nil
+	}
+
+	run := e.GetWorkflowRun()
+	repo := e.GetRepo().GetFullName()
+
+	color := colorNeutral
+	switch run.GetConclusion() {
+	case "success":
+		color = colorOpened
+	case "failure", "timed_out":
+		color = colorClosed
+	}
+
+	embed := eventEmbed(e.GetSender(), color)
+	embed.Title = truncate(fmt.Sprintf("[%s] Workflow %s: %s on %s", repo, run.GetConclusion(), run.GetName(), run.GetHeadBranch()), 255)
+	embed.URL = run.GetHTMLURL()
+
+	return &githubEvent{kind: eventWorkflowRun, repo: repo, embed: embed, branch: run.GetHeadBranch()}
+}
+
+// matches reports whether sub's filters let ev through. A filter only applies
+// to events that carry what it filters on: labels to issues and pull requests,
+// branches to pushes, pull requests and workflow runs, and paths to pushes.
+func (ev *githubEvent) matches(sub *database.Subscription) bool {
+	if len(sub.Events) > 0 && !containsFold(sub.Events, ev.kind) {
+		return false
+	}
+
+	if len(sub.Labels) > 0 && (ev.kind == eventIssues || ev.kind == eventPullRequest) {
+		labeled := false
+		for _, label := range ev.labels {
+			if containsFold(sub.Labels, label) {
+				labeled = true
+				break
+			}
+		}
+		if !labeled {
+			return false
+		}
+	}
+
+	if len(sub.Branches) > 0 && ev.branch != "" && !matchAny(sub.Branches, ev.branch, matchBranch) {
+		return false
+	}
+
+	if len(sub.Paths) > 0 && ev.kind == eventPush {
+		touched := false
+		for _, file := range ev.paths {
+			if matchAny(sub.Paths, file, matchPath) {
+				touched = true
+				break
+			}
+		}
+		if !touched {
+			return false
+		}
+	}
+
+	return true
+}
+
+// matchBranch matches a branch name against a glob such as "release/*".
+func matchBranch(pattern, branch string) bool {
+	ok, _ := path.Match(pattern, branch)
+	return ok
+}
+
+// matchPath matches a file against a glob such as "docs/*.md", or a directory
+// such as "web/" that contains it at any depth. Like .gitignore, a glob
+// without a slash such as "*.go" matches the file's name in any directory.
+func matchPath(pattern, file string) bool {
+	if ok, _ := path.Match(pattern, file); ok {
+		return true
+	}
+	if !strings.Contains(pattern, "/") {
+		if ok, _ := path.Match(pattern, path.Base(file)); ok {
+			return true
+		}
+	}
+	return strings.HasPrefix(file, strings.TrimSuffix(pattern, "/")+"/")
+}
+
+func matchAny(patterns []string, value string, match func(pattern, value string) bool) bool {
+	for _, pattern := range patterns {
+		if match(pattern, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func containsFold(values []string, value string) bool {
+	for _, v := range values {
+		if strings.EqualFold(v, value) {
+			return true
+		}
+	}
+	return false
+}
+
+func labelNames(labels []*github.Label) []string {
+	names := make([]string, 0, len(labels))
+	for _, label := range labels {
+		names = append(names, label.GetName())
+	}
+	return names
+}
+
+// eventEmbed returns an embed attributed to the GitHub user who triggered the event.
+func eventEmbed(sender *github.User, color int) *discordgo.MessageEmbed {
+	return &discordgo.MessageEmbed{
+		Color:     color,
+		Timestamp: time.Now().Format(time.RFC3339),
+		Author: &discordgo.MessageEmbedAuthor{
+			Name:    sender.GetLogin(),
+			URL:     sender.GetHTMLURL(),
+			IconURL: sender.GetAvatarURL(),
+		},
+	}
+}
@@ -0,0 +1,53 @@
+package bot
+
+import (
+	"testing"
+
+	"discord-github-bot/internal/database"
+)
+
+func TestEventMatches(t *testing.T) {
+	issue := &githubEvent{kind: eventIssues, repo: "owner/repo", labels: []string{"bug", "Frontend"}}
+	pullRequest := &githubEvent{kind: eventPullRequest, repo: "owner/repo", labels: []string{"docs"}, branch: "main"}
+	push := &githubEvent{kind: eventPush, repo: "owner/repo", branch: "release/1.2", paths: []string{"internal/bot/bot.go", "web/src/app.ts"}}
+	release := &githubEvent{kind: eventRelease, repo: "owner/repo"}
+
+	tests := []struct {
+		name string
+		ev   *githubEvent
+		sub  database.Subscription
+		want bool
+	}{
+		{"no filters", push, database.Subscription{}, true},
+		{"event listed", issue, database.Subscription{Events: []string{"issues", "push"}}, true},
+		{"event not listed", release, database.Subscription{Events: []string{"issues", "push"}}, false},
+
+		{"label matches case-insensitively", issue, database.Subscription{Labels: []string{"frontend"}}, true},
+		{"label missing", pullRequest, database.Subscription{Labels: []string{"bug"}}, false},
+		{"labels don't apply to pushes", push, database.Subscription{Labels: []string{"bug"}}, true},
+
+		{"branch glob", push, database.Subscription{Branches: []string{"release/*"}}, true},
+		{"branch glob doesn't cross slashes", push, database.Subscription{Branches: []string{"release*"}}, false},
+		{"pull request base branch", pullRequest, database.Subscription{Branches: []string{"develop"}}, false},
+		{"branches don't apply to issues", issue, database.Subscription{Branches: []string{"main"}}, true},
+
+		{"path glob without slash matches nested files", push, database.Subscription{Paths: []string{"*.go"}}, true},
+		{"path glob with slash matches the whole path", push, database.Subscription{Paths: []string{"internal/*.go"}}, false},
+		{"full path glob", push, database.Subscription{Paths: []string{"internal/bot/*.go"}}, true},
+		{"directory", push, database.Subscription{Paths: []string{"web/"}}, true},
+		{"directory without trailing slash", push, database.Subscription{Paths: []string{"web"}}, true},
+		{"directory prefix isn't a directory", push, database.Subscription{Paths: []string{"we"}}, false},
+		{"no file touched", push, database.Subscription{Paths: []string{"docs/", "*.md"}}, false},
+		{"paths don't apply to pull requests", pullRequest, database.Subscription{Paths: []string{"docs/"}}, true},
+
+		{"every filter must pass", push, database.Subscription{Events: []string{"push"}, Branches: []string{"main"}, Paths: []string{"*.go"}}, false},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := tt.ev.matches(&tt.sub); got != tt.want {
+				t.Errorf("matches(%+v) = %v, want %v", tt.sub, got, tt.want)
+			}
+		})
+	}
+}
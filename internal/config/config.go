@@ -2,7 +2,10 @@ package config
 
 import (
 	"errors"
+	"net/url"
 	"os"
+	"strconv"
+	"strings"
 )
 
 type Config struct {
@@ -11,11 +14,22 @@ type Config struct {
 	GitHubClientID      string
 	GitHubClientSecret  string
 	GitHubRedirectURL   string
+	GitHubURL           string
+	GitHubAPIURL        string
+	GitHubUploadURL     string
 	EncryptionKey       []byte
+	EncryptionKeyID     string
+	DecryptionKeys      map[string][]byte
 	OAuthServerPort     string
 	OAuthServerHost     string
 	PublicURL           string
+	OAuthServerEnabled  bool
 	DatabasePath        string
+	DatabaseURL         string
+	GitHubAppID         int64
+	GitHubAppPrivateKey []byte
+	GitHubWebhookSecret string
+	DiscordMessageContent bool
 }
 
 func Load() (*Config, error) {
@@ -44,6 +58,40 @@ func Load() (*Config, error) {
 		ghRedirectURL = "http://localhost:8080/callback"
 	}
 
+	// GitHub Enterprise Server deployments only need GITHUB_URL; the API and
+	// upload URLs default to the standard GHES paths under it.
+	ghURL := strings.TrimSuffix(os.Getenv("GITHUB_URL"), "/")
+	if ghURL == "" {
+		ghURL = "https://github.com"
+	}
+	if _, err := url.ParseRequestURI(ghURL); err != nil {
+		return nil, errors.New("GITHUB_URL must be a valid URL")
+	}
+
+	ghAPIURL := os.Getenv("GITHUB_API_URL")
+	ghUploadURL := os.Getenv("GITHUB_UPLOAD_URL")
+	if ghURL == "https://github.com" {
+		if ghAPIURL == "" {
+			ghAPIURL = "https://api.github.com/"
+		}
+		if ghUploadURL == "" {
+			ghUploadURL = "https://uploads.github.com/"
+		}
+	} else {
+		if ghAPIURL == "" {
+			ghAPIURL = ghURL + "/api/v3/"
+		}
+		if ghUploadURL == "" {
+			ghUploadURL = ghURL + "/api/uploads/"
+		}
+	}
+	if _, err := url.ParseRequestURI(ghAPIURL); err != nil {
+		return nil, errors.New("GITHUB_API_URL must be a valid URL")
+	}
+	if _, err := url.ParseRequestURI(ghUploadURL); err != nil {
+		return nil, errors.New("GITHUB_UPLOAD_URL must be a valid URL")
+	}
+
 	encryptionKey := os.Getenv("ENCRYPTION_KEY")
 	if encryptionKey == "" {
 		return nil, errors.New("ENCRYPTION_KEY is required (32 bytes for AES-256)")
@@ -52,6 +100,34 @@ func Load() (*Config, error) {
 		return nil, errors.New("ENCRYPTION_KEY must be exactly 32 bytes for AES-256")
 	}
 
+	// Stored tokens are tagged with the ID of the key that encrypted them, so
+	// retired keys listed in ENCRYPTION_OLD_KEYS can still decrypt them until
+	// they are re-encrypted with the rotate-keys command.
+	encryptionKeyID := os.Getenv("ENCRYPTION_KEY_ID")
+	if encryptionKeyID == "" {
+		encryptionKeyID = "1"
+	}
+	if strings.ContainsAny(encryptionKeyID, ":,") {
+		return nil, errors.New("ENCRYPTION_KEY_ID must not contain ':' or ','")
+	}
+
+	decryptionKeys := map[string][]byte{encryptionKeyID: []byte(encryptionKey)}
+	if oldKeys := os.Getenv("ENCRYPTION_OLD_KEYS"); oldKeys != "" {
+		for _, entry := range strings.Split(oldKeys, ",") {
+			keyID, key, found := strings.Cut(strings.TrimSpace(entry), ":")
+			if !found || keyID == "" {
+				return nil, errors.New("ENCRYPTION_OLD_KEYS must be a comma-separated list of id:key pairs")
+			}
+			if len(key) != 32 {
+				return nil, errors.New("every key in ENCRYPTION_OLD_KEYS must be exactly 32 bytes for AES-256")
+			}
+			if _, exists := decryptionKeys[keyID]; exists {
+				return nil, errors.New("ENCRYPTION_OLD_KEYS contains a duplicate key ID: " + keyID)
+			}
+			decryptionKeys[keyID] = []byte(key)
+		}
+	}
+
 	port := os.Getenv("OAUTH_SERVER_PORT")
 	if port == "" {
 		port = "8080"
@@ -67,21 +143,72 @@ func Load() (*Config, error) {
 		publicURL = "http://" + host + ":" + port
 	}
 
+	// The HTTP listener can be turned off on private networks where the callback
+	// URL is unreachable; users then authenticate with the device flow instead.
+	oauthServerEnabled := os.Getenv("OAUTH_SERVER_ENABLED") != "false"
+
 	dbPath := os.Getenv("DATABASE_PATH")
 	if dbPath == "" {
 		dbPath = "./bot.db"
 	}
 
+	// DATABASE_URL selects PostgreSQL instead of the SQLite file at DATABASE_PATH.
+	dbURL := os.Getenv("DATABASE_URL")
+	if dbURL != "" && !strings.HasPrefix(dbURL, "postgres://") && !strings.HasPrefix(dbURL, "postgresql://") {
+		return nil, errors.New("DATABASE_URL must be a postgres:// or postgresql:// URL")
+	}
+
+	// GitHub App installation auth is optional and only needed for bot-owned actions.
+	var githubAppID int64
+	var githubAppPrivateKey []byte
+	if rawAppID := os.Getenv("GITHUB_APP_ID"); rawAppID != "" {
+		id, err := strconv.ParseInt(rawAppID, 10, 64)
+		if err != nil {
+			return nil, errors.New("GITHUB_APP_ID must be a number")
+		}
+		githubAppID = id
+
+		if key := os.Getenv("GITHUB_APP_PRIVATE_KEY"); key != "" {
+			githubAppPrivateKey = []byte(key)
+		} else if keyPath := os.Getenv("GITHUB_APP_PRIVATE_KEY_PATH"); keyPath != "" {
+			key, err := os.ReadFile(keyPath)
+			if err != nil {
+				return nil, errors.New("failed to read GITHUB_APP_PRIVATE_KEY_PATH: " + err.Error())
+			}
+			githubAppPrivateKey = key
+		} else {
+			return nil, errors.New("GITHUB_APP_PRIVATE_KEY or GITHUB_APP_PRIVATE_KEY_PATH is required when GITHUB_APP_ID is set")
+		}
+	}
+
+	// Webhook events are only accepted when a secret is configured to verify them.
+	webhookSecret := os.Getenv("GITHUB_WEBHOOK_SECRET")
+
+	// Reading messages requires the privileged Message Content intent, which
+	// must also be enabled for the bot in the Discord Developer Portal.
+	messageContent := os.Getenv("DISCORD_MESSAGE_CONTENT_INTENT") == "true"
+
 	return &Config{
 		DiscordBotToken:      discordToken,
 		DiscordApplicationID: appID,
 		GitHubClientID:       ghClientID,
 		GitHubClientSecret:   ghClientSecret,
 		GitHubRedirectURL:    ghRedirectURL,
+		GitHubURL:            ghURL,
+		GitHubAPIURL:         ghAPIURL,
+		GitHubUploadURL:      ghUploadURL,
 		EncryptionKey:        []byte(encryptionKey),
+		EncryptionKeyID:      encryptionKeyID,
+		DecryptionKeys:       decryptionKeys,
 		OAuthServerPort:      port,
 		OAuthServerHost:      host,
 		PublicURL:            publicURL,
+		OAuthServerEnabled:   oauthServerEnabled,
 		DatabasePath:         dbPath,
+		DatabaseURL:          dbURL,
+		GitHubAppID:          githubAppID,
+		GitHubAppPrivateKey:  githubAppPrivateKey,
+		GitHubWebhookSecret:  webhookSecret,
+		DiscordMessageContent: messageContent,
 	}, nil
 }
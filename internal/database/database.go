@@ -7,105 +7,257 @@ import (
 	"database/sql"
 	"encoding/base64"
 	"errors"
+	"fmt"
 	"io"
+	"strings"
+	"time"
 
+	_ "github.com/lib/pq"
 	_ "github.com/mattn/go-sqlite3"
 )
 
+// Database implements Store on top of database/sql, for either SQLite or PostgreSQL.
 type Database struct {
-	db    *sql.DB
-	gcm   cipher.AEAD
+	db           *sql.DB
+	dialect      dialect
+	ciphers      map[string]cipher.AEAD
+	primaryKeyID string
+}
+
+// EncryptionKeys holds the AES-256 keys used to encrypt tokens at rest, by
+// key ID. New ciphertexts are always written with the primary key; the
+// others are only used to decrypt tokens that have not been rotated yet.
+type EncryptionKeys struct {
+	PrimaryID string
+	Keys      map[string][]byte
 }
 
 type User struct {
-	DiscordID      string
-	GitHubUsername string
-	GitHubToken    string
+	DiscordID          string
+	GitHubUsername     string
+	GitHubToken        string
+	GitHubRefreshToken string
+	TokenExpiry        time.Time
+	TokenStale         bool
 }
 
+// ChannelSettings holds defaults for a channel, thread or category; Discord
+// gives all three channel IDs.
 type ChannelSettings struct {
 	ChannelID      string
 	DefaultRepo    string
 	DefaultProject string
+	// ForumSync links issues created from posts in a forum channel to the post.
+	ForumSync bool
+	// UnfurlBy is the member whose GitHub token previews issue references
+	// posted in the channel, or empty if the channel doesn't turn previews on.
+	UnfurlBy string
+	// UnfurlOff turns previews off in the channel even when its parent channel
+	// or category turns them on.
+	UnfurlOff bool
 }
 
-func New(dbPath string, encryptionKey []byte) (*Database, error) {
-	db, err := sql.Open("sqlite3", dbPath)
-	if err != nil {
-		return nil, err
+// GuildSettings holds server-wide defaults used when no channel, thread or
+// category overrides them.
+type GuildSettings struct {
+	GuildID        string
+	DefaultRepo    string
+	DefaultProject string
+}
+
+// UserSettings holds a user's personal defaults, used in DMs and other places
+// without channel or server settings.
+type UserSettings struct {
+	DiscordID      string
+	DefaultRepo    string
+	DefaultProject string
+}
+
+// PermissionRule allows members of a guild to perform an action if they have
+// RoleID or every bit in Permission. Exactly one of the two is set.
+type PermissionRule struct {
+	GuildID    string
+	Action     string
+	RoleID     string
+	Permission int64
+}
+
+// AuditEntry records a GitHub write or settings change made through the bot.
+type AuditEntry struct {
+	ID          int64
+	CreatedAt   time.Time
+	DiscordID   string
+	GuildID     string
+	ChannelID   string
+	GitHubLogin string
+	Command     string
+	Repo        string
+	IssueNumber int
+	Project     string
+	Details     string
+	Success     bool
+	Error       string
+}
+
+// AuditFilter narrows ListAuditEntries. Zero-valued fields match everything.
+type AuditFilter struct {
+	GuildID     string
+	DiscordID   string
+	Command     string
+	Repo        string
+	IssueNumber int
+	Since       time.Time
+	Limit       int
+}
+
+// Subscription routes GitHub webhook events for Repo to a Discord channel.
+// Repo is stored lowercased, as GitHub repository names are case-insensitive.
+// Empty filters match everything.
+type Subscription struct {
+	ID        int64
+	GuildID   string
+	ChannelID string
+	Repo      string
+	CreatedBy string
+	Events    []string
+	Labels    []string
+	Paths     []string
+	Branches  []string
+}
+
+// IssueLink ties a Discord forum post to the GitHub issue created from it, so
+// replies, comments and closing are mirrored between them. Repo is stored lowercased.
+type IssueLink struct {
+	ThreadID    string
+	GuildID     string
+	Repo        string
+	IssueNumber int
+	CreatedBy   string
+}
+
+// New opens the SQLite database at dbPath, applying pending migrations.
+func New(dbPath string, keys EncryptionKeys) (*Database, error) {
+	return open(dialectSQLite, "sqlite3", dbPath, keys, true)
+}
+
+// NewPostgres opens the PostgreSQL database at databaseURL, applying pending migrations.
+func NewPostgres(databaseURL string, keys EncryptionKeys) (*Database, error) {
+	return open(dialectPostgres, "postgres", databaseURL, keys, true)
+}
+
+// NewWithoutMigrating opens the SQLite database at dbPath as it is, for
+// inspecting its schema with MigrationStatus.
+func NewWithoutMigrating(dbPath string, keys EncryptionKeys) (*Database, error) {
+	return open(dialectSQLite, "sqlite3", dbPath, keys, false)
+}
+
+// NewPostgresWithoutMigrating opens the PostgreSQL database at databaseURL as
+// it is, for inspecting its schema with MigrationStatus.
+func NewPostgresWithoutMigrating(databaseURL string, keys EncryptionKeys) (*Database, error) {
+	return open(dialectPostgres, "postgres", databaseURL, keys, false)
+}
+
+func open(dl dialect, driverName, dataSourceName string, keys EncryptionKeys, migrate bool) (*Database, error) {
+	if _, ok := keys.Keys[keys.PrimaryID]; !ok {
+		return nil, fmt.Errorf("primary encryption key %q is not configured", keys.PrimaryID)
 	}
 
-	block, err := aes.NewCipher(encryptionKey)
-	if err != nil {
-		return nil, err
+	ciphers := make(map[string]cipher.AEAD, len(keys.Keys))
+	for keyID, key := range keys.Keys {
+		block, err := aes.NewCipher(key)
+		if err != nil {
+			return nil, err
+		}
+
+		gcm, err := cipher.NewGCM(block)
+		if err != nil {
+			return nil, err
+		}
+		ciphers[keyID] = gcm
 	}
 
-	gcm, err := cipher.NewGCM(block)
+	db, err := sql.Open(driverName, dataSourceName)
 	if err != nil {
 		return nil, err
 	}
 
 	d := &Database{
-		db:  db,
-		gcm: gcm,
+		db:           db,
+		dialect:      dl,
+		ciphers:      ciphers,
+		primaryKeyID: keys.PrimaryID,
 	}
 
-	if err := d.createTables(); err != nil {
+	if !migrate {
+		return d, nil
+	}
+
+	if err := d.migrate(); err != nil {
+		db.Close()
 		return nil, err
 	}
 
 	return d, nil
 }
 
-func (d *Database) createTables() error {
-	schema := `
-	CREATE TABLE IF NOT EXISTS users (
-		discord_id TEXT PRIMARY KEY,
-		github_username TEXT NOT NULL,
-		github_token TEXT NOT NULL,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);
-
-	CREATE TABLE IF NOT EXISTS channel_settings (
-		channel_id TEXT PRIMARY KEY,
-		default_repo TEXT,
-		default_project TEXT,
-		created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
-		updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
-	);
-
-	CREATE INDEX IF NOT EXISTS idx_users_discord_id ON users(discord_id);
-	CREATE INDEX IF NOT EXISTS idx_channel_settings_channel_id ON channel_settings(channel_id);
-	`
-
-	_, err := d.db.Exec(schema)
-	return err
-}
-
 func (d *Database) encrypt(plaintext string) (string, error) {
-	nonce := make([]byte, d.gcm.NonceSize())
+	gcm := d.ciphers[d.primaryKeyID]
+
+	nonce := make([]byte, gcm.NonceSize())
 	if _, err := io.ReadFull(rand.Reader, nonce); err != nil {
 		return "", err
 	}
 
-	ciphertext := d.gcm.Seal(nonce, nonce, []byte(plaintext), nil)
-	return base64.StdEncoding.EncodeToString(ciphertext), nil
+	ciphertext := gcm.Seal(nonce, nonce, []byte(plaintext), nil)
+	return d.primaryKeyID + ":" + base64.StdEncoding.EncodeToString(ciphertext), nil
 }
 
 func (d *Database) decrypt(ciphertext string) (string, error) {
-	data, err := base64.StdEncoding.DecodeString(ciphertext)
+	keyID, encoded, versioned := strings.Cut(ciphertext, ":")
+	if !versioned {
+		return d.decryptLegacy(ciphertext)
+	}
+
+	gcm, ok := d.ciphers[keyID]
+	if !ok {
+		return "", fmt.Errorf("no decryption key configured for key ID %q", keyID)
+	}
+
+	return decryptWith(gcm, encoded)
+}
+
+// decryptLegacy decrypts ciphertexts written before key IDs were added by
+// trying every configured key. GCM authentication rejects the wrong ones.
+func (d *Database) decryptLegacy(ciphertext string) (string, error) {
+	if plaintext, err := decryptWith(d.ciphers[d.primaryKeyID], ciphertext); err == nil {
+		return plaintext, nil
+	}
+
+	for keyID, gcm := range d.ciphers {
+		if keyID == d.primaryKeyID {
+			continue
+		}
+		if plaintext, err := decryptWith(gcm, ciphertext); err == nil {
+			return plaintext, nil
+		}
+	}
+
+	return "", errors.New("failed to decrypt legacy ciphertext with any configured key")
+}
+
+func decryptWith(gcm cipher.AEAD, encoded string) (string, error) {
+	data, err := base64.StdEncoding.DecodeString(encoded)
 	if err != nil {
 		return "", err
 	}
 
-	nonceSize := d.gcm.NonceSize()
+	nonceSize := gcm.NonceSize()
 	if len(data) < nonceSize {
 		return "", errors.New("ciphertext too short")
 	}
 
 	nonce, ciphertextBytes := data[:nonceSize], data[nonceSize:]
-	plaintext, err := d.gcm.Open(nil, nonce, ciphertextBytes, nil)
+	plaintext, err := gcm.Open(nil, nonce, ciphertextBytes, nil)
 	if err != nil {
 		return "", err
 	}
@@ -113,36 +265,140 @@ func (d *Database) decrypt(ciphertext string) (string, error) {
 	return string(plaintext), nil
 }
 
+// isPrimary reports whether ciphertext is already encrypted under the primary key.
+func (d *Database) isPrimary(ciphertext string) bool {
+	return strings.HasPrefix(ciphertext, d.primaryKeyID+":")
+}
+
+// RotateEncryptionKey re-encrypts every stored token that is not already under
+// the primary key, inside a single transaction. It returns how many users were updated.
+func (d *Database) RotateEncryptionKey() (int, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return 0, err
+	}
+	defer tx.Rollback()
+
+	rows, err := tx.Query(`SELECT discord_id, github_token, github_refresh_token FROM users`)
+	if err != nil {
+		return 0, err
+	}
+
+	type rotatedUser struct {
+		discordID    string
+		token        string
+		refreshToken sql.NullString
+	}
+
+	var pending []rotatedUser
+	for rows.Next() {
+		var user rotatedUser
+		if err := rows.Scan(&user.discordID, &user.token, &user.refreshToken); err != nil {
+			rows.Close()
+			return 0, err
+		}
+
+		if d.isPrimary(user.token) && (!user.refreshToken.Valid || d.isPrimary(user.refreshToken.String)) {
+			continue
+		}
+		pending = append(pending, user)
+	}
+	rows.Close()
+	if err := rows.Err(); err != nil {
+		return 0, err
+	}
+
+	for _, user := range pending {
+		token, err := d.reencrypt(user.token)
+		if err != nil {
+			return 0, fmt.Errorf("failed to re-encrypt token for %s: %w", user.discordID, err)
+		}
+
+		refreshToken := user.refreshToken
+		if refreshToken.Valid {
+			refreshToken.String, err = d.reencrypt(refreshToken.String)
+			if err != nil {
+				return 0, fmt.Errorf("failed to re-encrypt refresh token for %s: %w", user.discordID, err)
+			}
+		}
+
+		_, err = tx.Exec(d.dialect.rebind(
+			`UPDATE users SET github_token = ?, github_refresh_token = ? WHERE discord_id = ?`),
+			token, refreshToken, user.discordID,
+		)
+		if err != nil {
+			return 0, err
+		}
+	}
+
+	if err := tx.Commit(); err != nil {
+		return 0, err
+	}
+
+	return len(pending), nil
+}
+
+func (d *Database) reencrypt(ciphertext string) (string, error) {
+	plaintext, err := d.decrypt(ciphertext)
+	if err != nil {
+		return "", err
+	}
+
+	return d.encrypt(plaintext)
+}
+
 func (d *Database) SaveUser(user *User) error {
 	encryptedToken, err := d.encrypt(user.GitHubToken)
 	if err != nil {
 		return err
 	}
 
+	var encryptedRefreshToken sql.NullString
+	if user.GitHubRefreshToken != "" {
+		encrypted, err := d.encrypt(user.GitHubRefreshToken)
+		if err != nil {
+			return err
+		}
+		encryptedRefreshToken = sql.NullString{String: encrypted, Valid: true}
+	}
+
+	var tokenExpiry sql.NullInt64
+	if !user.TokenExpiry.IsZero() {
+		tokenExpiry = sql.NullInt64{Int64: user.TokenExpiry.Unix(), Valid: true}
+	}
+
 	query := `
-	INSERT INTO users (discord_id, github_username, github_token, updated_at)
-	VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+	INSERT INTO users (discord_id, github_username, github_token, github_refresh_token, token_expiry, updated_at)
+	VALUES (?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
 	ON CONFLICT(discord_id) DO UPDATE SET
 		github_username = excluded.github_username,
 		github_token = excluded.github_token,
+		github_refresh_token = excluded.github_refresh_token,
+		token_expiry = excluded.token_expiry,
+		token_stale = FALSE,
 		updated_at = CURRENT_TIMESTAMP
 	`
 
-	_, err = d.db.Exec(query, user.DiscordID, user.GitHubUsername, encryptedToken)
+	_, err = d.db.Exec(d.dialect.rebind(query), user.DiscordID, user.GitHubUsername, encryptedToken, encryptedRefreshToken, tokenExpiry)
 	return err
 }
 
-func (d *Database) GetUser(discordID string) (*User, error) {
-	query := `SELECT discord_id, github_username, github_token FROM users WHERE discord_id = ?`
+// userColumns lists the users columns read by scanUser, in order.
+const userColumns = `discord_id, github_username, github_token, github_refresh_token, token_expiry, token_stale`
+
+// rowScanner is implemented by both *sql.Row and *sql.Rows.
+type rowScanner interface {
+	Scan(dest ...interface{}) error
+}
 
+func (d *Database) scanUser(row rowScanner) (*User, error) {
 	var user User
 	var encryptedToken string
+	var encryptedRefreshToken sql.NullString
+	var tokenExpiry sql.NullInt64
 
-	err := d.db.QueryRow(query, discordID).Scan(&user.DiscordID, &user.GitHubUsername, &encryptedToken)
+	err := row.Scan(&user.DiscordID, &user.GitHubUsername, &encryptedToken, &encryptedRefreshToken, &tokenExpiry, &user.TokenStale)
 	if err != nil {
-		if err == sql.ErrNoRows {
-			return nil, nil
-		}
 		return nil, err
 	}
 
@@ -152,33 +408,90 @@ func (d *Database) GetUser(discordID string) (*User, error) {
 	}
 
 	user.GitHubToken = token
+
+	if encryptedRefreshToken.Valid {
+		refreshToken, err := d.decrypt(encryptedRefreshToken.String)
+		if err != nil {
+			return nil, err
+		}
+		user.GitHubRefreshToken = refreshToken
+	}
+
+	if tokenExpiry.Valid {
+		user.TokenExpiry = time.Unix(tokenExpiry.Int64, 0)
+	}
+
 	return &user, nil
 }
 
+func (d *Database) GetUser(discordID string) (*User, error) {
+	query := `SELECT ` + userColumns + ` FROM users WHERE discord_id = ?`
+
+	user, err := d.scanUser(d.db.QueryRow(d.dialect.rebind(query), discordID))
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return user, nil
+}
+
+// ListUsers returns every linked user with decrypted tokens.
+func (d *Database) ListUsers() ([]*User, error) {
+	rows, err := d.db.Query(`SELECT ` + userColumns + ` FROM users ORDER BY discord_id`)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var users []*User
+	for rows.Next() {
+		user, err := d.scanUser(rows)
+		if err != nil {
+			return nil, err
+		}
+		users = append(users, user)
+	}
+
+	return users, rows.Err()
+}
+
+// MarkUserStale flags a user whose token was found to be revoked or missing scopes.
+// The flag is cleared the next time the user is saved.
+func (d *Database) MarkUserStale(discordID string) error {
+	_, err := d.db.Exec(d.dialect.rebind("UPDATE users SET token_stale = TRUE, updated_at = CURRENT_TIMESTAMP WHERE discord_id = ?"), discordID)
+	return err
+}
+
 func (d *Database) DeleteUser(discordID string) error {
-	_, err := d.db.Exec("DELETE FROM users WHERE discord_id = ?", discordID)
+	_, err := d.db.Exec(d.dialect.rebind("DELETE FROM users WHERE discord_id = ?"), discordID)
 	return err
 }
 
 func (d *Database) SaveChannelSettings(settings *ChannelSettings) error {
 	query := `
-	INSERT INTO channel_settings (channel_id, default_repo, default_project, updated_at)
-	VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+	INSERT INTO channel_settings (channel_id, default_repo, default_project, forum_sync, unfurl_by, unfurl_off, updated_at)
+	VALUES (?, ?, ?, ?, ?, ?, CURRENT_TIMESTAMP)
 	ON CONFLICT(channel_id) DO UPDATE SET
 		default_repo = excluded.default_repo,
 		default_project = excluded.default_project,
+		forum_sync = excluded.forum_sync,
+		unfurl_by = excluded.unfurl_by,
+		unfurl_off = excluded.unfurl_off,
 		updated_at = CURRENT_TIMESTAMP
 	`
 
-	_, err := d.db.Exec(query, settings.ChannelID, settings.DefaultRepo, settings.DefaultProject)
+	_, err := d.db.Exec(d.dialect.rebind(query), settings.ChannelID, settings.DefaultRepo, settings.DefaultProject, settings.ForumSync, settings.UnfurlBy, settings.UnfurlOff)
 	return err
 }
 
 func (d *Database) GetChannelSettings(channelID string) (*ChannelSettings, error) {
-	query := `SELECT channel_id, default_repo, default_project FROM channel_settings WHERE channel_id = ?`
+	query := `SELECT channel_id, default_repo, default_project, forum_sync, unfurl_by, unfurl_off FROM channel_settings WHERE channel_id = ?`
 
 	var settings ChannelSettings
-	err := d.db.QueryRow(query, channelID).Scan(&settings.ChannelID, &settings.DefaultRepo, &settings.DefaultProject)
+	err := d.db.QueryRow(d.dialect.rebind(query), channelID).Scan(&settings.ChannelID, &settings.DefaultRepo, &settings.DefaultProject, &settings.ForumSync, &settings.UnfurlBy, &settings.UnfurlOff)
 	if err != nil {
 		if err == sql.ErrNoRows {
 			return &ChannelSettings{ChannelID: channelID}, nil
@@ -189,6 +502,458 @@ func (d *Database) GetChannelSettings(channelID string) (*ChannelSettings, error
 	return &settings, nil
 }
 
+func (d *Database) SaveGuildSettings(settings *GuildSettings) error {
+	query := `
+	INSERT INTO guild_settings (guild_id, default_repo, default_project, updated_at)
+	VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+	ON CONFLICT(guild_id) DO UPDATE SET
+		default_repo = excluded.default_repo,
+		default_project = excluded.default_project,
+		updated_at = CURRENT_TIMESTAMP
+	`
+
+	_, err := d.db.Exec(d.dialect.rebind(query), settings.GuildID, settings.DefaultRepo, settings.DefaultProject)
+	return err
+}
+
+func (d *Database) GetGuildSettings(guildID string) (*GuildSettings, error) {
+	query := `SELECT guild_id, default_repo, default_project FROM guild_settings WHERE guild_id = ?`
+
+	var settings GuildSettings
+	err := d.db.QueryRow(d.dialect.rebind(query), guildID).Scan(&settings.GuildID, &settings.DefaultRepo, &settings.DefaultProject)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return &GuildSettings{GuildID: guildID}, nil
+		}
+		return nil, err
+	}
+
+	return &settings, nil
+}
+
+func (d *Database) SaveUserSettings(settings *UserSettings) error {
+	query := `
+	INSERT INTO user_settings (discord_id, default_repo, default_project, updated_at)
+	VALUES (?, ?, ?, CURRENT_TIMESTAMP)
+	ON CONFLICT(discord_id) DO UPDATE SET
+		default_repo = excluded.default_repo,
+		default_project = excluded.default_project,
+		updated_at = CURRENT_TIMESTAMP
+	`
+
+	_, err := d.db.Exec(d.dialect.rebind(query), settings.DiscordID, settings.DefaultRepo, settings.DefaultProject)
+	return err
+}
+
+func (d *Database) GetUserSettings(discordID string) (*UserSettings, error) {
+	query := `SELECT discord_id, default_repo, default_project FROM user_settings WHERE discord_id = ?`
+
+	var settings UserSettings
+	err := d.db.QueryRow(d.dialect.rebind(query), discordID).Scan(&settings.DiscordID, &settings.DefaultRepo, &settings.DefaultProject)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return &UserSettings{DiscordID: discordID}, nil
+		}
+		return nil, err
+	}
+
+	return &settings, nil
+}
+
+// AddPermissionRule stores rule. Adding a rule that already exists is a no-op.
+func (d *Database) AddPermissionRule(rule *PermissionRule) error {
+	query := `
+	INSERT INTO command_permissions (guild_id, action, role_id, permission)
+	VALUES (?, ?, ?, ?)
+	ON CONFLICT(guild_id, action, role_id, permission) DO NOTHING
+	`
+
+	_, err := d.db.Exec(d.dialect.rebind(query), rule.GuildID, rule.Action, rule.RoleID, rule.Permission)
+	return err
+}
+
+// RemovePermissionRule deletes rule and reports whether it existed.
+func (d *Database) RemovePermissionRule(rule *PermissionRule) (bool, error) {
+	query := `DELETE FROM command_permissions WHERE guild_id = ? AND action = ? AND role_id = ? AND permission = ?`
+
+	result, err := d.db.Exec(d.dialect.rebind(query), rule.GuildID, rule.Action, rule.RoleID, rule.Permission)
+	if err != nil {
+		return false, err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return n > 0, nil
+}
+
+// ClearPermissionRules deletes every rule for action in guildID, restoring the default policy.
+func (d *Database) ClearPermissionRules(guildID, action string) error {
+	_, err := d.db.Exec(d.dialect.rebind("DELETE FROM command_permissions WHERE guild_id = ? AND action = ?"), guildID, action)
+	return err
+}
+
+// ListPermissionRules returns every rule configured for guildID.
+func (d *Database) ListPermissionRules(guildID string) ([]*PermissionRule, error) {
+	query := `
+	SELECT guild_id, action, role_id, permission FROM command_permissions
+	WHERE guild_id = ?
+	ORDER BY action, role_id, permission
+	`
+
+	rows, err := d.db.Query(d.dialect.rebind(query), guildID)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var rules []*PermissionRule
+	for rows.Next() {
+		var rule PermissionRule
+		if err := rows.Scan(&rule.GuildID, &rule.Action, &rule.RoleID, &rule.Permission); err != nil {
+			return nil, err
+		}
+		rules = append(rules, &rule)
+	}
+
+	return rules, rows.Err()
+}
+
+// SaveOAuthState records a pending login for discordID that is valid until expiresAt.
+func (d *Database) SaveOAuthState(state, discordID string, expiresAt time.Time) error {
+	query := `INSERT INTO oauth_states (state, discord_id, expires_at) VALUES (?, ?, ?)`
+
+	_, err := d.db.Exec(d.dialect.rebind(query), state, discordID, expiresAt.Unix())
+	return err
+}
+
+// ConsumeOAuthState atomically deletes a pending login and returns its Discord ID.
+// It returns an empty string if the state does not exist or has expired.
+func (d *Database) ConsumeOAuthState(state string) (string, error) {
+	query := `DELETE FROM oauth_states WHERE state = ? AND expires_at > ? RETURNING discord_id`
+
+	var discordID string
+	err := d.db.QueryRow(d.dialect.rebind(query), state, time.Now().Unix()).Scan(&discordID)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return "", nil
+		}
+		return "", err
+	}
+
+	return discordID, nil
+}
+
+// DeleteExpiredOAuthStates removes pending logins that have expired and returns how many were removed.
+func (d *Database) DeleteExpiredOAuthStates() (int64, error) {
+	result, err := d.db.Exec(d.dialect.rebind("DELETE FROM oauth_states WHERE expires_at <= ?"), time.Now().Unix())
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
+// RecordAudit appends entry to the audit log. CreatedAt defaults to now.
+func (d *Database) RecordAudit(entry *AuditEntry) error {
+	if entry.CreatedAt.IsZero() {
+		entry.CreatedAt = time.Now()
+	}
+
+	query := `
+	INSERT INTO audit_log (created_at, discord_id, guild_id, channel_id, github_login, command, repo, issue_number, project, details, success, error)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?, ?)
+	`
+
+	_, err := d.db.Exec(d.dialect.rebind(query),
+		entry.CreatedAt.Unix(), entry.DiscordID, entry.GuildID, entry.ChannelID, entry.GitHubLogin, entry.Command,
+		entry.Repo, entry.IssueNumber, entry.Project, entry.Details, entry.Success, entry.Error,
+	)
+	return err
+}
+
+// ListAuditEntries returns the audit entries matching filter, newest first.
+func (d *Database) ListAuditEntries(filter AuditFilter) ([]*AuditEntry, error) {
+	query := `
+	SELECT id, created_at, discord_id, guild_id, channel_id, github_login, command, repo, issue_number, project, details, success, error
+	FROM audit_log WHERE 1 = 1`
+	var args []interface{}
+
+	if filter.GuildID != "" {
+		query += ` AND guild_id = ?`
+		args = append(args, filter.GuildID)
+	}
+	if filter.DiscordID != "" {
+		query += ` AND discord_id = ?`
+		args = append(args, filter.DiscordID)
+	}
+	if filter.Command != "" {
+		query += ` AND command = ?`
+		args = append(args, filter.Command)
+	}
+	if filter.Repo != "" {
+		query += ` AND LOWER(repo) = LOWER(?)`
+		args = append(args, filter.Repo)
+	}
+	if filter.IssueNumber != 0 {
+		query += ` AND issue_number = ?`
+		args = append(args, filter.IssueNumber)
+	}
+	if !filter.Since.IsZero() {
+		query += ` AND created_at >= ?`
+		args = append(args, filter.Since.Unix())
+	}
+
+	query += ` ORDER BY created_at DESC, id DESC`
+	if filter.Limit > 0 {
+		query += ` LIMIT ?`
+		args = append(args, filter.Limit)
+	}
+
+	rows, err := d.db.Query(d.dialect.rebind(query), args...)
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	var entries []*AuditEntry
+	for rows.Next() {
+		var entry AuditEntry
+		var createdAt int64
+		err := rows.Scan(&entry.ID, &createdAt, &entry.DiscordID, &entry.GuildID, &entry.ChannelID, &entry.GitHubLogin,
+			&entry.Command, &entry.Repo, &entry.IssueNumber, &entry.Project, &entry.Details, &entry.Success, &entry.Error)
+		if err != nil {
+			return nil, err
+		}
+		entry.CreatedAt = time.Unix(createdAt, 0)
+		entries = append(entries, &entry)
+	}
+
+	return entries, rows.Err()
+}
+
+// SaveSubscription subscribes a channel to a repository, replacing any
+// existing subscription of that channel to the same repository.
+func (d *Database) SaveSubscription(sub *Subscription) error {
+	sub.Repo = strings.ToLower(sub.Repo)
+
+	query := `
+	INSERT INTO subscriptions (guild_id, channel_id, repo, created_by, events, labels, paths, branches)
+	VALUES (?, ?, ?, ?, ?, ?, ?, ?)
+	ON CONFLICT(channel_id, repo) DO UPDATE SET
+		guild_id = excluded.guild_id,
+		created_by = excluded.created_by,
+		events = excluded.events,
+		labels = excluded.labels,
+		paths = excluded.paths,
+		branches = excluded.branches
+	`
+
+	_, err := d.db.Exec(d.dialect.rebind(query), sub.GuildID, sub.ChannelID, sub.Repo, sub.CreatedBy,
+		joinList(sub.Events), joinList(sub.Labels), joinList(sub.Paths), joinList(sub.Branches))
+	return err
+}
+
+const subscriptionColumns = `id, guild_id, channel_id, repo, created_by, events, labels, paths, branches`
+
+func scanSubscriptions(rows *sql.Rows) ([]*Subscription, error) {
+	defer rows.Close()
+
+	var subs []*Subscription
+	for rows.Next() {
+		var sub Subscription
+		var events, labels, paths, branches string
+		err := rows.Scan(&sub.ID, &sub.GuildID, &sub.ChannelID, &sub.Repo, &sub.CreatedBy, &events, &labels, &paths, &branches)
+		if err != nil {
+			return nil, err
+		}
+		sub.Events = splitList(events)
+		sub.Labels = splitList(labels)
+		sub.Paths = splitList(paths)
+		sub.Branches = splitList(branches)
+		subs = append(subs, &sub)
+	}
+
+	return subs, rows.Err()
+}
+
+// joinList stores a filter list in a single column. Filter values never
+// contain newlines, so they are used as the separator.
+func joinList(values []string) string {
+	return strings.Join(values, "\n")
+}
+
+func splitList(value string) []string {
+	if value == "" {
+		return nil
+	}
+	return strings.Split(value, "\n")
+}
+
+// GetSubscription returns channelID's subscription to repo, or nil if there is none.
+func (d *Database) GetSubscription(channelID, repo string) (*Subscription, error) {
+	query := `SELECT ` + subscriptionColumns + ` FROM subscriptions WHERE channel_id = ? AND repo = ?`
+
+	rows, err := d.db.Query(d.dialect.rebind(query), channelID, strings.ToLower(repo))
+	if err != nil {
+		return nil, err
+	}
+
+	subs, err := scanSubscriptions(rows)
+	if err != nil || len(subs) == 0 {
+		return nil, err
+	}
+
+	return subs[0], nil
+}
+
+// ListSubscriptionsByRepo returns every channel subscription to repo.
+func (d *Database) ListSubscriptionsByRepo(repo string) ([]*Subscription, error) {
+	query := `SELECT ` + subscriptionColumns + ` FROM subscriptions WHERE repo = ? ORDER BY id`
+
+	rows, err := d.db.Query(d.dialect.rebind(query), strings.ToLower(repo))
+	if err != nil {
+		return nil, err
+	}
+
+	return scanSubscriptions(rows)
+}
+
+// ListSubscriptionsByChannel returns every repository subscription of channelID, by repo.
+func (d *Database) ListSubscriptionsByChannel(channelID string) ([]*Subscription, error) {
+	query := `SELECT ` + subscriptionColumns + ` FROM subscriptions WHERE channel_id = ? ORDER BY repo`
+
+	rows, err := d.db.Query(d.dialect.rebind(query), channelID)
+	if err != nil {
+		return nil, err
+	}
+
+	return scanSubscriptions(rows)
+}
+
+// DeleteSubscription unsubscribes channelID from repo and reports whether it was subscribed.
+func (d *Database) DeleteSubscription(channelID, repo string) (bool, error) {
+	query := `DELETE FROM subscriptions WHERE channel_id = ? AND repo = ?`
+
+	result, err := d.db.Exec(d.dialect.rebind(query), channelID, strings.ToLower(repo))
+	if err != nil {
+		return false, err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return n > 0, nil
+}
+
+// SaveIssueLink links a forum post to an issue. A post or issue can only be
+// linked once; saving a link for either again fails.
+func (d *Database) SaveIssueLink(link *IssueLink) error {
+	link.Repo = strings.ToLower(link.Repo)
+
+	query := `INSERT INTO issue_links (thread_id, guild_id, repo, issue_number, created_by) VALUES (?, ?, ?, ?, ?)`
+
+	_, err := d.db.Exec(d.dialect.rebind(query), link.ThreadID, link.GuildID, link.Repo, link.IssueNumber, link.CreatedBy)
+	return err
+}
+
+const issueLinkColumns = `thread_id, guild_id, repo, issue_number, created_by`
+
+func (d *Database) getIssueLink(where string, args ...interface{}) (*IssueLink, error) {
+	query := `SELECT ` + issueLinkColumns + ` FROM issue_links WHERE ` + where
+
+	var link IssueLink
+	err := d.db.QueryRow(d.dialect.rebind(query), args...).Scan(&link.ThreadID, &link.GuildID, &link.Repo, &link.IssueNumber, &link.CreatedBy)
+	if err != nil {
+		if err == sql.ErrNoRows {
+			return nil, nil
+		}
+		return nil, err
+	}
+
+	return &link, nil
+}
+
+// GetIssueLinkByThread returns the link for a forum post, or nil if it isn't linked.
+func (d *Database) GetIssueLinkByThread(threadID string) (*IssueLink, error) {
+	return d.getIssueLink(`thread_id = ?`, threadID)
+}
+
+// GetIssueLinkByIssue returns the link for an issue, or nil if it isn't linked.
+func (d *Database) GetIssueLinkByIssue(repo string, issueNumber int) (*IssueLink, error) {
+	return d.getIssueLink(`repo = ? AND issue_number = ?`, strings.ToLower(repo), issueNumber)
+}
+
+func (d *Database) DeleteIssueLink(threadID string) error {
+	_, err := d.db.Exec(d.dialect.rebind("DELETE FROM issue_links WHERE thread_id = ?"), threadID)
+	return err
+}
+
+// RecordWebhookDelivery remembers a webhook delivery ID and reports whether
+// it is new. GitHub reuses the ID when redelivering, so false means a duplicate.
+func (d *Database) RecordWebhookDelivery(deliveryID string, receivedAt time.Time) (bool, error) {
+	query := `INSERT INTO webhook_deliveries (delivery_id, received_at) VALUES (?, ?) ON CONFLICT(delivery_id) DO NOTHING`
+
+	result, err := d.db.Exec(d.dialect.rebind(query), deliveryID, receivedAt.Unix())
+	if err != nil {
+		return false, err
+	}
+
+	n, err := result.RowsAffected()
+	if err != nil {
+		return false, err
+	}
+
+	return n > 0, nil
+}
+
+// DeleteWebhookDelivery forgets a delivery ID, so a redelivery of it is handled
+// again. The targets it reached are kept.
+func (d *Database) DeleteWebhookDelivery(deliveryID string) error {
+	_, err := d.db.Exec(d.dialect.rebind("DELETE FROM webhook_deliveries WHERE delivery_id = ?"), deliveryID)
+	return err
+}
+
+// RecordWebhookDeliveryTarget remembers that a delivery reached target, such as
+// a channel, so handling a redelivery of it doesn't post there again.
+func (d *Database) RecordWebhookDeliveryTarget(deliveryID, target string, deliveredAt time.Time) error {
+	query := `INSERT INTO webhook_delivery_targets (delivery_id, target, delivered_at) VALUES (?, ?, ?) ON CONFLICT(delivery_id, target) DO NOTHING`
+
+	_, err := d.db.Exec(d.dialect.rebind(query), deliveryID, target, deliveredAt.Unix())
+	return err
+}
+
+// HasWebhookDeliveryTarget reports whether a delivery already reached target.
+func (d *Database) HasWebhookDeliveryTarget(deliveryID, target string) (bool, error) {
+	query := `SELECT COUNT(*) FROM webhook_delivery_targets WHERE delivery_id = ? AND target = ?`
+
+	var count int
+	if err := d.db.QueryRow(d.dialect.rebind(query), deliveryID, target).Scan(&count); err != nil {
+		return false, err
+	}
+
+	return count > 0, nil
+}
+
+// DeleteWebhookDeliveriesBefore forgets deliveries, and the targets they
+// reached, from before cutoff and returns how many deliveries were removed.
+func (d *Database) DeleteWebhookDeliveriesBefore(cutoff time.Time) (int64, error) {
+	if _, err := d.db.Exec(d.dialect.rebind("DELETE FROM webhook_delivery_targets WHERE delivered_at < ?"), cutoff.Unix()); err != nil {
+		return 0, err
+	}
+
+	result, err := d.db.Exec(d.dialect.rebind("DELETE FROM webhook_deliveries WHERE received_at < ?"), cutoff.Unix())
+	if err != nil {
+		return 0, err
+	}
+
+	return result.RowsAffected()
+}
+
 func (d *Database) Close() error {
 	return d.db.Close()
 }
@@ -0,0 +1,35 @@
+package database
+
+import (
+	"strconv"
+	"strings"
+)
+
+// dialect identifies the SQL database behind a Database. Queries are written
+// with SQLite's "?" placeholders and rebound for PostgreSQL.
+type dialect string
+
+const (
+	dialectSQLite   dialect = "sqlite"
+	dialectPostgres dialect = "postgres"
+)
+
+// rebind rewrites "?" placeholders into the dialect's native form.
+func (dl dialect) rebind(query string) string {
+	if dl != dialectPostgres {
+		return query
+	}
+
+	var b strings.Builder
+	n := 0
+	for _, r := range query {
+		if r == '?' {
+			n++
+			b.WriteString("$" + strconv.Itoa(n))
+			continue
+		}
+		b.WriteRune(r)
+	}
+
+	return b.String()
+}
@@ -0,0 +1,245 @@
+package database
+
+import (
+	"database/sql"
+	"embed"
+	"fmt"
+	"log"
+	"path"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// Migrations live in one directory per dialect and are applied in order of the
+// numeric prefix of their file name, e.g. 0002_add_subscriptions.sql. Both
+// dialects must define the same versions. Applied migrations must never be
+// edited; add a new file instead.
+//
+//go:embed migrations/sqlite/*.sql migrations/postgres/*.sql
+var migrationFiles embed.FS
+
+// migrationLockID is the PostgreSQL advisory lock key held while applying a
+// migration, so replicas starting together don't apply it twice.
+const migrationLockID = 7310448210
+
+type migration struct {
+	version int
+	name    string
+	sql     string
+}
+
+func loadMigrations(dl dialect) ([]migration, error) {
+	dir := path.Join("migrations", string(dl))
+	entries, err := migrationFiles.ReadDir(dir)
+	if err != nil {
+		return nil, err
+	}
+
+	var migrations []migration
+	seen := make(map[int]string)
+	for _, entry := range entries {
+		name := entry.Name()
+		prefix, _, found := strings.Cut(name, "_")
+		if !found {
+			return nil, fmt.Errorf("migration %s must be named <version>_<description>.sql", name)
+		}
+
+		version, err := strconv.Atoi(prefix)
+		if err != nil || version <= 0 {
+			return nil, fmt.Errorf("migration %s has an invalid version prefix", name)
+		}
+		if other, exists := seen[version]; exists {
+			return nil, fmt.Errorf("migrations %s and %s share version %d", other, name, version)
+		}
+		seen[version] = name
+
+		contents, err := migrationFiles.ReadFile(path.Join(dir, name))
+		if err != nil {
+			return nil, err
+		}
+
+		migrations = append(migrations, migration{version: version, name: name, sql: string(contents)})
+	}
+
+	sort.Slice(migrations, func(i, j int) bool {
+		return migrations[i].version < migrations[j].version
+	})
+
+	return migrations, nil
+}
+
+// migrate brings the schema up to the latest embedded migration, applying
+// each pending migration in its own transaction.
+func (d *Database) migrate() error {
+	_, err := d.db.Exec(`
+	CREATE TABLE IF NOT EXISTS schema_migrations (
+		version INTEGER PRIMARY KEY,
+		name TEXT NOT NULL,
+		applied_at TIMESTAMP DEFAULT CURRENT_TIMESTAMP
+	)`)
+	if err != nil {
+		return err
+	}
+
+	migrations, err := loadMigrations(d.dialect)
+	if err != nil {
+		return err
+	}
+
+	current, err := d.SchemaVersion()
+	if err != nil {
+		return err
+	}
+
+	if current == 0 && d.dialect == dialectSQLite {
+		if err := d.upgradeLegacySchema(); err != nil {
+			return fmt.Errorf("failed to upgrade pre-migration schema: %w", err)
+		}
+	}
+
+	for _, m := range migrations {
+		if m.version <= current {
+			continue
+		}
+
+		applied, err := d.applyMigration(m)
+		if err != nil {
+			return fmt.Errorf("failed to apply migration %s: %w", m.name, err)
+		}
+		if applied {
+			log.Printf("Applied database migration %s", m.name)
+		}
+	}
+
+	return nil
+}
+
+// applyMigration applies m unless another process already has. It reports
+// whether the migration was applied by this call.
+func (d *Database) applyMigration(m migration) (bool, error) {
+	tx, err := d.db.Begin()
+	if err != nil {
+		return false, err
+	}
+	defer tx.Rollback()
+
+	if d.dialect == dialectPostgres {
+		if _, err := tx.Exec(`SELECT pg_advisory_xact_lock($1)`, migrationLockID); err != nil {
+			return false, err
+		}
+	}
+
+	var exists int
+	err = tx.QueryRow(d.dialect.rebind(`SELECT COUNT(*) FROM schema_migrations WHERE version = ?`), m.version).Scan(&exists)
+	if err != nil {
+		return false, err
+	}
+	if exists > 0 {
+		return false, nil
+	}
+
+	if _, err := tx.Exec(m.sql); err != nil {
+		return false, err
+	}
+
+	if _, err := tx.Exec(d.dialect.rebind(`INSERT INTO schema_migrations (version, name) VALUES (?, ?)`), m.version, m.name); err != nil {
+		return false, err
+	}
+
+	if err := tx.Commit(); err != nil {
+		return false, err
+	}
+
+	return true, nil
+}
+
+// SchemaVersion returns the version of the most recently applied migration,
+// or 0 if none have been applied.
+func (d *Database) SchemaVersion() (int, error) {
+	var version sql.NullInt64
+	if err := d.db.QueryRow(`SELECT MAX(version) FROM schema_migrations`).Scan(&version); err != nil {
+		return 0, err
+	}
+
+	return int(version.Int64), nil
+}
+
+// MigrationStatus returns the version of the most recently applied migration
+// and the names of the embedded migrations that are still pending. Unlike
+// SchemaVersion it also works on databases that have never been migrated.
+func (d *Database) MigrationStatus() (int, []string, error) {
+	migrations, err := loadMigrations(d.dialect)
+	if err != nil {
+		return 0, nil, err
+	}
+
+	query := `SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = ?`
+	if d.dialect == dialectPostgres {
+		query = `SELECT COUNT(*) FROM information_schema.tables WHERE table_schema = current_schema() AND table_name = ?`
+	}
+	var tables int
+	if err := d.db.QueryRow(d.dialect.rebind(query), "schema_migrations").Scan(&tables); err != nil {
+		return 0, nil, err
+	}
+
+	current := 0
+	if tables > 0 {
+		if current, err = d.SchemaVersion(); err != nil {
+			return 0, nil, err
+		}
+	}
+
+	var pending []string
+	for _, m := range migrations {
+		if m.version > current {
+			pending = append(pending, m.name)
+		}
+	}
+
+	return current, pending, nil
+}
+
+// upgradeLegacySchema adds the columns that databases created before the
+// migration framework may be missing, so migration 1 applies cleanly on top.
+func (d *Database) upgradeLegacySchema() error {
+	var count int
+	if err := d.db.QueryRow(`SELECT COUNT(*) FROM sqlite_master WHERE type = 'table' AND name = 'users'`).Scan(&count); err != nil {
+		return err
+	}
+	if count == 0 {
+		return nil
+	}
+
+	if err := d.addColumnIfMissing("users", "github_refresh_token", "TEXT"); err != nil {
+		return err
+	}
+	if err := d.addColumnIfMissing("users", "token_expiry", "INTEGER"); err != nil {
+		return err
+	}
+	return d.addColumnIfMissing("users", "token_stale", "BOOLEAN NOT NULL DEFAULT 0")
+}
+
+func (d *Database) addColumnIfMissing(table, column, columnType string) error {
+	rows, err := d.db.Query("SELECT name FROM pragma_table_info(?)", table)
+	if err != nil {
+		return err
+	}
+	defer rows.Close()
+
+	for rows.Next() {
+		var name string
+		if err := rows.Scan(&name); err != nil {
+			return err
+		}
+		if name == column {
+			return nil
+		}
+	}
+	if err := rows.Err(); err != nil {
+		return err
+	}
+
+	_, err = d.db.Exec(fmt.Sprintf("ALTER TABLE %s ADD COLUMN %s %s", table, column, columnType))
+	return err
+}
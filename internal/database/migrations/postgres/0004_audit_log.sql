@@ -0,0 +1,17 @@
+CREATE TABLE IF NOT EXISTS audit_log (
+	id BIGSERIAL PRIMARY KEY,
+	created_at BIGINT NOT NULL,
+	discord_id TEXT NOT NULL,
+	guild_id TEXT NOT NULL DEFAULT '',
+	channel_id TEXT NOT NULL DEFAULT '',
+	github_login TEXT NOT NULL DEFAULT '',
+	command TEXT NOT NULL,
+	repo TEXT NOT NULL DEFAULT '',
+	issue_number INTEGER NOT NULL DEFAULT 0,
+	project TEXT NOT NULL DEFAULT '',
+	details TEXT NOT NULL DEFAULT '',
+	success BOOLEAN NOT NULL,
+	error TEXT NOT NULL DEFAULT ''
+);
+
+CREATE INDEX IF NOT EXISTS idx_audit_log_guild_created_at ON audit_log(guild_id, created_at);
@@ -0,0 +1,18 @@
+CREATE TABLE IF NOT EXISTS subscriptions (
+	id BIGSERIAL PRIMARY KEY,
+	guild_id TEXT NOT NULL DEFAULT '',
+	channel_id TEXT NOT NULL,
+	repo TEXT NOT NULL,
+	created_by TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (channel_id, repo)
+);
+
+CREATE INDEX IF NOT EXISTS idx_subscriptions_repo ON subscriptions(repo);
+
+CREATE TABLE IF NOT EXISTS webhook_deliveries (
+	delivery_id TEXT PRIMARY KEY,
+	received_at BIGINT NOT NULL
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_deliveries_received_at ON webhook_deliveries(received_at);
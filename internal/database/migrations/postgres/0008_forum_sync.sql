@@ -0,0 +1,11 @@
+ALTER TABLE channel_settings ADD COLUMN forum_sync BOOLEAN NOT NULL DEFAULT FALSE;
+
+CREATE TABLE IF NOT EXISTS issue_links (
+	thread_id TEXT PRIMARY KEY,
+	guild_id TEXT NOT NULL DEFAULT '',
+	repo TEXT NOT NULL,
+	issue_number INTEGER NOT NULL,
+	created_by TEXT NOT NULL DEFAULT '',
+	created_at TIMESTAMPTZ DEFAULT CURRENT_TIMESTAMP,
+	UNIQUE (repo, issue_number)
+);
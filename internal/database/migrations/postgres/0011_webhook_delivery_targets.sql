@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS webhook_delivery_targets (
+	delivery_id TEXT NOT NULL,
+	target TEXT NOT NULL,
+	delivered_at BIGINT NOT NULL,
+	PRIMARY KEY (delivery_id, target)
+);
+
+CREATE INDEX IF NOT EXISTS idx_webhook_delivery_targets_delivered_at ON webhook_delivery_targets(delivered_at);
@@ -0,0 +1,30 @@
+CREATE TABLE IF NOT EXISTS users (
+	discord_id TEXT PRIMARY KEY,
+	github_username TEXT NOT NULL,
+	github_token TEXT NOT NULL,
+	github_refresh_token TEXT,
+	token_expiry INTEGER,
+	token_stale BOOLEAN NOT NULL DEFAULT 0,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS channel_settings (
+	channel_id TEXT PRIMARY KEY,
+	default_repo TEXT,
+	default_project TEXT,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE TABLE IF NOT EXISTS oauth_states (
+	state TEXT PRIMARY KEY,
+	discord_id TEXT NOT NULL,
+	expires_at INTEGER NOT NULL,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
+
+CREATE INDEX IF NOT EXISTS idx_users_discord_id ON users(discord_id);
+CREATE INDEX IF NOT EXISTS idx_channel_settings_channel_id ON channel_settings(channel_id);
+CREATE INDEX IF NOT EXISTS idx_oauth_states_expires_at ON oauth_states(expires_at);
+
@@ -0,0 +1,8 @@
+CREATE TABLE IF NOT EXISTS command_permissions (
+	guild_id TEXT NOT NULL,
+	action TEXT NOT NULL,
+	role_id TEXT NOT NULL DEFAULT '',
+	permission INTEGER NOT NULL DEFAULT 0,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	PRIMARY KEY (guild_id, action, role_id, permission)
+);
@@ -0,0 +1,7 @@
+CREATE TABLE IF NOT EXISTS user_settings (
+	discord_id TEXT PRIMARY KEY,
+	default_repo TEXT,
+	default_project TEXT,
+	created_at DATETIME DEFAULT CURRENT_TIMESTAMP,
+	updated_at DATETIME DEFAULT CURRENT_TIMESTAMP
+);
@@ -0,0 +1,4 @@
+ALTER TABLE subscriptions ADD COLUMN events TEXT NOT NULL DEFAULT '';
+ALTER TABLE subscriptions ADD COLUMN labels TEXT NOT NULL DEFAULT '';
+ALTER TABLE subscriptions ADD COLUMN paths TEXT NOT NULL DEFAULT '';
+ALTER TABLE subscriptions ADD COLUMN branches TEXT NOT NULL DEFAULT '';
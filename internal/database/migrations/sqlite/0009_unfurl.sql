@@ -0,0 +1 @@
+ALTER TABLE channel_settings ADD COLUMN unfurl_by TEXT NOT NULL DEFAULT '';
@@ -0,0 +1 @@
+ALTER TABLE channel_settings ADD COLUMN unfurl_off BOOLEAN NOT NULL DEFAULT 0;
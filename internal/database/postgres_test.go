@@ -0,0 +1,60 @@
+package database_test
+
+import (
+	"database/sql"
+	"fmt"
+	"net/url"
+	"os"
+	"testing"
+	"time"
+
+	"discord-github-bot/internal/database"
+	"discord-github-bot/internal/database/storetest"
+)
+
+// TestPostgresStore runs the conformance suite against the PostgreSQL server at
+// TEST_DATABASE_URL. Each subtest gets its own schema, which is dropped afterwards.
+func TestPostgresStore(t *testing.T) {
+	databaseURL := os.Getenv("TEST_DATABASE_URL")
+	if databaseURL == "" {
+		t.Skip("TEST_DATABASE_URL is not set")
+	}
+
+	admin, err := sql.Open("postgres", databaseURL)
+	if err != nil {
+		t.Fatalf("sql.Open: %v", err)
+	}
+	t.Cleanup(func() { admin.Close() })
+
+	storetest.Run(t, func(t *testing.T) database.Store {
+		schema := fmt.Sprintf("storetest_%d", time.Now().UnixNano())
+		if _, err := admin.Exec("CREATE SCHEMA " + schema); err != nil {
+			t.Fatalf("CREATE SCHEMA: %v", err)
+		}
+		t.Cleanup(func() {
+			if _, err := admin.Exec("DROP SCHEMA " + schema + " CASCADE"); err != nil {
+				t.Errorf("DROP SCHEMA: %v", err)
+			}
+		})
+
+		db, err := database.NewPostgres(withSearchPath(t, databaseURL, schema), storetest.Keys)
+		if err != nil {
+			t.Fatalf("NewPostgres: %v", err)
+		}
+		return db
+	})
+}
+
+// withSearchPath returns databaseURL with its connections confined to schema.
+func withSearchPath(t *testing.T, databaseURL, schema string) string {
+	u, err := url.Parse(databaseURL)
+	if err != nil {
+		t.Fatalf("invalid TEST_DATABASE_URL: %v", err)
+	}
+
+	query := u.Query()
+	query.Set("search_path", schema)
+	u.RawQuery = query.Encode()
+
+	return u.String()
+}
@@ -0,0 +1,49 @@
+package database_test
+
+import (
+	"path/filepath"
+	"testing"
+
+	"discord-github-bot/internal/database"
+	"discord-github-bot/internal/database/storetest"
+)
+
+func TestSQLiteStore(t *testing.T) {
+	storetest.Run(t, func(t *testing.T) database.Store {
+		db, err := database.New(filepath.Join(t.TempDir(), "bot.db"), storetest.Keys)
+		if err != nil {
+			t.Fatalf("New: %v", err)
+		}
+		return db
+	})
+}
+
+func TestMigrationStatusWithoutMigrating(t *testing.T) {
+	dbPath := filepath.Join(t.TempDir(), "bot.db")
+
+	db, err := database.NewWithoutMigrating(dbPath, storetest.Keys)
+	if err != nil {
+		t.Fatalf("NewWithoutMigrating: %v", err)
+	}
+	version, pending, err := db.MigrationStatus()
+	db.Close()
+	if err != nil {
+		t.Fatalf("MigrationStatus: %v", err)
+	}
+	if version != 0 || len(pending) == 0 {
+		t.Fatalf("MigrationStatus of a new database = %d, %v, want 0 and pending migrations", version, pending)
+	}
+
+	db, err = database.New(dbPath, storetest.Keys)
+	if err != nil {
+		t.Fatalf("New: %v", err)
+	}
+	defer db.Close()
+	version, err = db.SchemaVersion()
+	if err != nil {
+		t.Fatalf("SchemaVersion: %v", err)
+	}
+	if version != len(pending) {
+		t.Fatalf("SchemaVersion after migrating = %d, want %d", version, len(pending))
+	}
+}
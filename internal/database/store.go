@@ -0,0 +1,55 @@
+package database
+
+import "time"
+
+// Store is the persistence layer used by the bot and the OAuth server. It is
+// implemented by Database for both SQLite and PostgreSQL.
+type Store interface {
+	SaveUser(user *User) error
+	GetUser(discordID string) (*User, error)
+	ListUsers() ([]*User, error)
+	DeleteUser(discordID string) error
+	MarkUserStale(discordID string) error
+
+	SaveChannelSettings(settings *ChannelSettings) error
+	GetChannelSettings(channelID string) (*ChannelSettings, error)
+	SaveGuildSettings(settings *GuildSettings) error
+	GetGuildSettings(guildID string) (*GuildSettings, error)
+	SaveUserSettings(settings *UserSettings) error
+	GetUserSettings(discordID string) (*UserSettings, error)
+
+	AddPermissionRule(rule *PermissionRule) error
+	RemovePermissionRule(rule *PermissionRule) (bool, error)
+	ClearPermissionRules(guildID, action string) error
+	ListPermissionRules(guildID string) ([]*PermissionRule, error)
+
+	RecordAudit(entry *AuditEntry) error
+	ListAuditEntries(filter AuditFilter) ([]*AuditEntry, error)
+
+	SaveSubscription(sub *Subscription) error
+	GetSubscription(channelID, repo string) (*Subscription, error)
+	ListSubscriptionsByRepo(repo string) ([]*Subscription, error)
+	ListSubscriptionsByChannel(channelID string) ([]*Subscription, error)
+	DeleteSubscription(channelID, repo string) (bool, error)
+	RecordWebhookDelivery(deliveryID string, receivedAt time.Time) (bool, error)
+	DeleteWebhookDelivery(deliveryID string) error
+	RecordWebhookDeliveryTarget(deliveryID, target string, deliveredAt time.Time) error
+	HasWebhookDeliveryTarget(deliveryID, target string) (bool, error)
+	DeleteWebhookDeliveriesBefore(cutoff time.Time) (int64, error)
+
+	SaveIssueLink(link *IssueLink) error
+	GetIssueLinkByThread(threadID string) (*IssueLink, error)
+	GetIssueLinkByIssue(repo string, issueNumber int) (*IssueLink, error)
+	DeleteIssueLink(threadID string) error
+
+	SaveOAuthState(state, discordID string, expiresAt time.Time) error
+	ConsumeOAuthState(state string) (string, error)
+	DeleteExpiredOAuthStates() (int64, error)
+
+	RotateEncryptionKey() (int, error)
+	SchemaVersion() (int, error)
+	MigrationStatus() (int, []string, error)
+	Close() error
+}
+
+var _ Store = (*Database)(nil)
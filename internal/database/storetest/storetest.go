@@ -0,0 +1,677 @@
+// Package storetest is a conformance suite that every database.Store
+// implementation must pass. Backends run it from their own tests:
+//
+//	storetest.Run(t, func(t *testing.T) database.Store {
+//		db, err := database.New(filepath.Join(t.TempDir(), "bot.db"), storetest.Keys)
+//		if err != nil {
+//			t.Fatal(err)
+//		}
+//		return db
+//	})
+//
+// newStore must return an empty store; Run closes it when each subtest ends.
+package storetest
+
+import (
+	"reflect"
+	"testing"
+	"time"
+
+	"discord-github-bot/internal/database"
+)
+
+// Keys is a fixed key set backends can use to open stores under test.
+var Keys = database.EncryptionKeys{
+	PrimaryID: "test",
+	Keys:      map[string][]byte{"test": []byte("0123456789abcdef0123456789abcdef")},
+}
+
+// Run executes the conformance suite against stores created by newStore.
+func Run(t *testing.T, newStore func(t *testing.T) database.Store) {
+	tests := []struct {
+		name string
+		fn   func(t *testing.T, store database.Store)
+	}{
+		{"Users", testUsers},
+		{"UserTokenRefresh", testUserTokenRefresh},
+		{"StaleUsers", testStaleUsers},
+		{"ChannelSettings", testChannelSettings},
+		{"GuildSettings", testGuildSettings},
+		{"UserSettings", testUserSettings},
+		{"PermissionRules", testPermissionRules},
+		{"AuditLog", testAuditLog},
+		{"Subscriptions", testSubscriptions},
+		{"WebhookDeliveries", testWebhookDeliveries},
+		{"WebhookDeliveryTargets", testWebhookDeliveryTargets},
+		{"IssueLinks", testIssueLinks},
+		{"OAuthStates", testOAuthStates},
+		{"RotateEncryptionKey", testRotateEncryptionKey},
+		{"SchemaVersion", testSchemaVersion},
+		{"MigrationStatus", testMigrationStatus},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			store := newStore(t)
+			defer store.Close()
+			tt.fn(t, store)
+		})
+	}
+}
+
+func testUsers(t *testing.T, store database.Store) {
+	user, err := store.GetUser("missing")
+	if err != nil {
+		t.Fatalf("GetUser on missing user: %v", err)
+	}
+	if user != nil {
+		t.Fatalf("GetUser on missing user = %+v, want nil", user)
+	}
+
+	saved := &database.User{DiscordID: "1", GitHubUsername: "octocat", GitHubToken: "token-1"}
+	if err := store.SaveUser(saved); err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+
+	user, err = store.GetUser("1")
+	if err != nil {
+		t.Fatalf("GetUser: %v", err)
+	}
+	if user == nil || user.GitHubUsername != "octocat" || user.GitHubToken != "token-1" {
+		t.Fatalf("GetUser = %+v, want octocat with token-1", user)
+	}
+
+	saved.GitHubUsername = "hubot"
+	saved.GitHubToken = "token-2"
+	if err := store.SaveUser(saved); err != nil {
+		t.Fatalf("SaveUser update: %v", err)
+	}
+
+	if err := store.SaveUser(&database.User{DiscordID: "2", GitHubUsername: "monalisa", GitHubToken: "token-3"}); err != nil {
+		t.Fatalf("SaveUser second user: %v", err)
+	}
+
+	users, err := store.ListUsers()
+	if err != nil {
+		t.Fatalf("ListUsers: %v", err)
+	}
+	if len(users) != 2 || users[0].GitHubUsername != "hubot" || users[0].GitHubToken != "token-2" {
+		t.Fatalf("ListUsers = %+v, want updated hubot first of 2 users", users)
+	}
+
+	if err := store.DeleteUser("1"); err != nil {
+		t.Fatalf("DeleteUser: %v", err)
+	}
+
+	user, err = store.GetUser("1")
+	if err != nil {
+		t.Fatalf("GetUser after delete: %v", err)
+	}
+	if user != nil {
+		t.Fatalf("GetUser after delete = %+v, want nil", user)
+	}
+}
+
+func testUserTokenRefresh(t *testing.T, store database.Store) {
+	expiry := time.Now().Add(8 * time.Hour).Truncate(time.Second)
+	saved := &database.User{
+		DiscordID:          "1",
+		GitHubUsername:     "octocat",
+		GitHubToken:        "access",
+		GitHubRefreshToken: "refresh",
+		TokenExpiry:        expiry,
+	}
+	if err := store.SaveUser(saved); err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+
+	user, err := store.GetUser("1")
+	if err != nil {
+		t.Fatalf("GetUser: %v", err)
+	}
+	if user.GitHubRefreshToken != "refresh" || !user.TokenExpiry.Equal(expiry) {
+		t.Fatalf("GetUser = %+v, want refresh token and expiry %v", user, expiry)
+	}
+
+	// Clearing the refresh token must not leave the old one behind.
+	saved.GitHubRefreshToken = ""
+	saved.TokenExpiry = time.Time{}
+	if err := store.SaveUser(saved); err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+
+	user, err = store.GetUser("1")
+	if err != nil {
+		t.Fatalf("GetUser: %v", err)
+	}
+	if user.GitHubRefreshToken != "" || !user.TokenExpiry.IsZero() {
+		t.Fatalf("GetUser = %+v, want no refresh token or expiry", user)
+	}
+}
+
+func testStaleUsers(t *testing.T, store database.Store) {
+	saved := &database.User{DiscordID: "1", GitHubUsername: "octocat", GitHubToken: "token"}
+	if err := store.SaveUser(saved); err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+
+	if err := store.MarkUserStale("1"); err != nil {
+		t.Fatalf("MarkUserStale: %v", err)
+	}
+
+	user, err := store.GetUser("1")
+	if err != nil {
+		t.Fatalf("GetUser: %v", err)
+	}
+	if !user.TokenStale {
+		t.Fatal("TokenStale = false after MarkUserStale, want true")
+	}
+
+	if err := store.SaveUser(saved); err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+
+	user, err = store.GetUser("1")
+	if err != nil {
+		t.Fatalf("GetUser: %v", err)
+	}
+	if user.TokenStale {
+		t.Fatal("TokenStale = true after SaveUser, want false")
+	}
+}
+
+func testChannelSettings(t *testing.T, store database.Store) {
+	settings, err := store.GetChannelSettings("c1")
+	if err != nil {
+		t.Fatalf("GetChannelSettings on unset channel: %v", err)
+	}
+	if settings.ChannelID != "c1" || settings.DefaultRepo != "" || settings.DefaultProject != "" {
+		t.Fatalf("GetChannelSettings on unset channel = %+v, want empty settings for c1", settings)
+	}
+
+	settings.DefaultRepo = "owner/repo"
+	if err := store.SaveChannelSettings(settings); err != nil {
+		t.Fatalf("SaveChannelSettings: %v", err)
+	}
+
+	settings.DefaultProject = "owner/1"
+	settings.ForumSync = true
+	settings.UnfurlBy = "u1"
+	if err := store.SaveChannelSettings(settings); err != nil {
+		t.Fatalf("SaveChannelSettings update: %v", err)
+	}
+
+	settings, err = store.GetChannelSettings("c1")
+	if err != nil {
+		t.Fatalf("GetChannelSettings: %v", err)
+	}
+	if settings.DefaultRepo != "owner/repo" || settings.DefaultProject != "owner/1" || !settings.ForumSync || settings.UnfurlBy != "u1" {
+		t.Fatalf("GetChannelSettings = %+v, want owner/repo, owner/1, forum sync and unfurling by u1", settings)
+	}
+
+	settings.UnfurlBy = ""
+	settings.UnfurlOff = true
+	if err := store.SaveChannelSettings(settings); err != nil {
+		t.Fatalf("SaveChannelSettings unfurl off: %v", err)
+	}
+
+	settings, err = store.GetChannelSettings("c1")
+	if err != nil {
+		t.Fatalf("GetChannelSettings: %v", err)
+	}
+	if settings.UnfurlBy != "" || !settings.UnfurlOff {
+		t.Fatalf("GetChannelSettings = %+v, want unfurling explicitly off", settings)
+	}
+}
+
+func testGuildSettings(t *testing.T, store database.Store) {
+	settings, err := store.GetGuildSettings("g1")
+	if err != nil {
+		t.Fatalf("GetGuildSettings on unset guild: %v", err)
+	}
+	if settings.GuildID != "g1" || settings.DefaultRepo != "" || settings.DefaultProject != "" {
+		t.Fatalf("GetGuildSettings on unset guild = %+v, want empty settings for g1", settings)
+	}
+
+	settings.DefaultRepo = "owner/repo"
+	settings.DefaultProject = "owner/1"
+	if err := store.SaveGuildSettings(settings); err != nil {
+		t.Fatalf("SaveGuildSettings: %v", err)
+	}
+
+	settings, err = store.GetGuildSettings("g1")
+	if err != nil {
+		t.Fatalf("GetGuildSettings: %v", err)
+	}
+	if settings.DefaultRepo != "owner/repo" || settings.DefaultProject != "owner/1" {
+		t.Fatalf("GetGuildSettings = %+v, want owner/repo and owner/1", settings)
+	}
+
+	// Guild defaults are separate from channel defaults with the same ID.
+	channel, err := store.GetChannelSettings("g1")
+	if err != nil {
+		t.Fatalf("GetChannelSettings: %v", err)
+	}
+	if channel.DefaultRepo != "" {
+		t.Fatalf("GetChannelSettings = %+v, want no repo", channel)
+	}
+}
+
+func testUserSettings(t *testing.T, store database.Store) {
+	settings, err := store.GetUserSettings("u1")
+	if err != nil {
+		t.Fatalf("GetUserSettings on unset user: %v", err)
+	}
+	if settings.DiscordID != "u1" || settings.DefaultRepo != "" || settings.DefaultProject != "" {
+		t.Fatalf("GetUserSettings on unset user = %+v, want empty settings for u1", settings)
+	}
+
+	settings.DefaultRepo = "owner/repo"
+	if err := store.SaveUserSettings(settings); err != nil {
+		t.Fatalf("SaveUserSettings: %v", err)
+	}
+
+	settings.DefaultProject = "owner/1"
+	if err := store.SaveUserSettings(settings); err != nil {
+		t.Fatalf("SaveUserSettings update: %v", err)
+	}
+
+	settings, err = store.GetUserSettings("u1")
+	if err != nil {
+		t.Fatalf("GetUserSettings: %v", err)
+	}
+	if settings.DefaultRepo != "owner/repo" || settings.DefaultProject != "owner/1" {
+		t.Fatalf("GetUserSettings = %+v, want owner/repo and owner/1", settings)
+	}
+}
+
+func testPermissionRules(t *testing.T, store database.Store) {
+	rules, err := store.ListPermissionRules("g1")
+	if err != nil {
+		t.Fatalf("ListPermissionRules on unset guild: %v", err)
+	}
+	if len(rules) != 0 {
+		t.Fatalf("ListPermissionRules on unset guild = %d rules, want 0", len(rules))
+	}
+
+	role := &database.PermissionRule{GuildID: "g1", Action: "settings", RoleID: "r1"}
+	perm := &database.PermissionRule{GuildID: "g1", Action: "close", Permission: 1 << 4}
+	other := &database.PermissionRule{GuildID: "g2", Action: "settings", RoleID: "r1"}
+	for _, rule := range []*database.PermissionRule{role, perm, other, role} {
+		if err := store.AddPermissionRule(rule); err != nil {
+			t.Fatalf("AddPermissionRule(%+v): %v", rule, err)
+		}
+	}
+
+	rules, err = store.ListPermissionRules("g1")
+	if err != nil {
+		t.Fatalf("ListPermissionRules: %v", err)
+	}
+	if len(rules) != 2 {
+		t.Fatalf("ListPermissionRules = %d rules, want 2", len(rules))
+	}
+	if *rules[0] != *perm || *rules[1] != *role {
+		t.Fatalf("ListPermissionRules = %+v, %+v; want %+v, %+v", rules[0], rules[1], perm, role)
+	}
+
+	removed, err := store.RemovePermissionRule(role)
+	if err != nil || !removed {
+		t.Fatalf("RemovePermissionRule = %v, %v; want true, nil", removed, err)
+	}
+	removed, err = store.RemovePermissionRule(role)
+	if err != nil || removed {
+		t.Fatalf("RemovePermissionRule twice = %v, %v; want false, nil", removed, err)
+	}
+
+	if err := store.ClearPermissionRules("g1", "close"); err != nil {
+		t.Fatalf("ClearPermissionRules: %v", err)
+	}
+	rules, err = store.ListPermissionRules("g1")
+	if err != nil {
+		t.Fatalf("ListPermissionRules: %v", err)
+	}
+	if len(rules) != 0 {
+		t.Fatalf("ListPermissionRules after clear = %d rules, want 0", len(rules))
+	}
+
+	rules, err = store.ListPermissionRules("g2")
+	if err != nil {
+		t.Fatalf("ListPermissionRules: %v", err)
+	}
+	if len(rules) != 1 {
+		t.Fatalf("ListPermissionRules for other guild = %d rules, want 1", len(rules))
+	}
+}
+
+func testAuditLog(t *testing.T, store database.Store) {
+	now := time.Now()
+	entries := []*database.AuditEntry{
+		{CreatedAt: now.Add(-2 * time.Hour), DiscordID: "u1", GuildID: "g1", Command: "gh-issue-create", Repo: "owner/repo", IssueNumber: 1, Success: true},
+		{CreatedAt: now.Add(-time.Hour), DiscordID: "u2", GuildID: "g1", Command: "gh-issue-close", Repo: "owner/repo", IssueNumber: 1, Success: false, Error: "not found"},
+		{CreatedAt: now, DiscordID: "u1", GuildID: "g1", GitHubLogin: "octocat", Command: "gh-issue-comment", Repo: "owner/other", IssueNumber: 2, Success: true},
+		{CreatedAt: now, DiscordID: "u1", GuildID: "g2", Command: "gh-issue-create", Repo: "owner/repo", Success: true},
+	}
+	for _, entry := range entries {
+		if err := store.RecordAudit(entry); err != nil {
+			t.Fatalf("RecordAudit: %v", err)
+		}
+	}
+
+	got, err := store.ListAuditEntries(database.AuditFilter{GuildID: "g1"})
+	if err != nil {
+		t.Fatalf("ListAuditEntries: %v", err)
+	}
+	if len(got) != 3 {
+		t.Fatalf("ListAuditEntries for g1 = %d entries, want 3", len(got))
+	}
+	if got[0].Command != "gh-issue-comment" || got[0].GitHubLogin != "octocat" || !got[0].Success {
+		t.Fatalf("newest entry = %+v, want the gh-issue-comment by octocat", got[0])
+	}
+	if got[1].Success || got[1].Error != "not found" {
+		t.Fatalf("failed entry = %+v, want Success false and its error", got[1])
+	}
+	if got[0].CreatedAt.Unix() != now.Unix() {
+		t.Fatalf("CreatedAt = %v, want %v", got[0].CreatedAt, now)
+	}
+
+	filters := []struct {
+		filter database.AuditFilter
+		want   int
+	}{
+		{database.AuditFilter{GuildID: "g1", DiscordID: "u1"}, 2},
+		{database.AuditFilter{GuildID: "g1", Command: "gh-issue-close"}, 1},
+		{database.AuditFilter{GuildID: "g1", Repo: "Owner/Repo"}, 2},
+		{database.AuditFilter{GuildID: "g1", Repo: "owner/repo", IssueNumber: 1}, 2},
+		{database.AuditFilter{GuildID: "g1", Since: now.Add(-90 * time.Minute)}, 2},
+		{database.AuditFilter{GuildID: "g1", Limit: 1}, 1},
+		{database.AuditFilter{}, 4},
+	}
+	for _, tt := range filters {
+		got, err := store.ListAuditEntries(tt.filter)
+		if err != nil {
+			t.Fatalf("ListAuditEntries(%+v): %v", tt.filter, err)
+		}
+		if len(got) != tt.want {
+			t.Errorf("ListAuditEntries(%+v) = %d entries, want %d", tt.filter, len(got), tt.want)
+		}
+	}
+}
+
+func testSubscriptions(t *testing.T, store database.Store) {
+	subs := []*database.Subscription{
+		{GuildID: "g1", ChannelID: "c1", Repo: "Owner/Repo", CreatedBy: "u1"},
+		{GuildID: "g1", ChannelID: "c2", Repo: "owner/repo", CreatedBy: "u1"},
+		{GuildID: "g1", ChannelID: "c1", Repo: "owner/other", CreatedBy: "u1"},
+		{GuildID: "g1", ChannelID: "c1", Repo: "owner/repo", CreatedBy: "u2", Events: []string{"issues", "pull_request"}, Labels: []string{"frontend", "ui"}},
+	}
+	for _, sub := range subs {
+		if err := store.SaveSubscription(sub); err != nil {
+			t.Fatalf("SaveSubscription(%+v): %v", sub, err)
+		}
+	}
+
+	got, err := store.ListSubscriptionsByRepo("OWNER/REPO")
+	if err != nil {
+		t.Fatalf("ListSubscriptionsByRepo: %v", err)
+	}
+	if len(got) != 2 {
+		t.Fatalf("ListSubscriptionsByRepo = %d subscriptions, want 2", len(got))
+	}
+	if got[0].ChannelID != "c1" || got[0].Repo != "owner/repo" || got[0].CreatedBy != "u2" {
+		t.Fatalf("first subscription = %+v, want c1 on owner/repo replaced by u2", got[0])
+	}
+
+	if !reflect.DeepEqual(got[0].Events, []string{"issues", "pull_request"}) || !reflect.DeepEqual(got[0].Labels, []string{"frontend", "ui"}) {
+		t.Fatalf("first subscription filters = %v %v, want [issues pull_request] [frontend ui]", got[0].Events, got[0].Labels)
+	}
+	if got[0].Paths != nil || got[0].Branches != nil {
+		t.Fatalf("first subscription unset filters = %v %v, want nil", got[0].Paths, got[0].Branches)
+	}
+
+	got, err = store.ListSubscriptionsByRepo("owner/missing")
+	if err != nil {
+		t.Fatalf("ListSubscriptionsByRepo on unknown repo: %v", err)
+	}
+	if len(got) != 0 {
+		t.Fatalf("ListSubscriptionsByRepo on unknown repo = %d subscriptions, want 0", len(got))
+	}
+
+	got, err = store.ListSubscriptionsByChannel("c1")
+	if err != nil {
+		t.Fatalf("ListSubscriptionsByChannel: %v", err)
+	}
+	if len(got) != 2 || got[0].Repo != "owner/other" || got[1].Repo != "owner/repo" {
+		t.Fatalf("ListSubscriptionsByChannel = %+v, want owner/other and owner/repo", got)
+	}
+
+	sub, err := store.GetSubscription("c2", "Owner/Repo")
+	if err != nil || sub == nil || sub.CreatedBy != "u1" {
+		t.Fatalf("GetSubscription = %+v, %v; want c2 on owner/repo by u1", sub, err)
+	}
+
+	removed, err := store.DeleteSubscription("c2", "OWNER/repo")
+	if err != nil || !removed {
+		t.Fatalf("DeleteSubscription = %v, %v; want true, nil", removed, err)
+	}
+
+	removed, err = store.DeleteSubscription("c2", "owner/repo")
+	if err != nil || removed {
+		t.Fatalf("DeleteSubscription of missing subscription = %v, %v; want false, nil", removed, err)
+	}
+
+	sub, err = store.GetSubscription("c2", "owner/repo")
+	if err != nil || sub != nil {
+		t.Fatalf("GetSubscription after delete = %+v, %v; want nil, nil", sub, err)
+	}
+}
+
+func testIssueLinks(t *testing.T, store database.Store) {
+	link := &database.IssueLink{ThreadID: "t1", GuildID: "g1", Repo: "Owner/Repo", IssueNumber: 42, CreatedBy: "u1"}
+	if err := store.SaveIssueLink(link); err != nil {
+		t.Fatalf("SaveIssueLink: %v", err)
+	}
+
+	if err := store.SaveIssueLink(&database.IssueLink{ThreadID: "t2", Repo: "owner/repo", IssueNumber: 42}); err == nil {
+		t.Fatalf("SaveIssueLink of an already linked issue succeeded, want error")
+	}
+
+	got, err := store.GetIssueLinkByThread("t1")
+	if err != nil {
+		t.Fatalf("GetIssueLinkByThread: %v", err)
+	}
+	if got == nil || got.Repo != "owner/repo" || got.IssueNumber != 42 || got.GuildID != "g1" || got.CreatedBy != "u1" {
+		t.Fatalf("GetIssueLinkByThread = %+v, want owner/repo#42 in g1 by u1", got)
+	}
+
+	got, err = store.GetIssueLinkByIssue("OWNER/repo", 42)
+	if err != nil {
+		t.Fatalf("GetIssueLinkByIssue: %v", err)
+	}
+	if got == nil || got.ThreadID != "t1" {
+		t.Fatalf("GetIssueLinkByIssue = %+v, want thread t1", got)
+	}
+
+	got, err = store.GetIssueLinkByIssue("owner/repo", 43)
+	if err != nil || got != nil {
+		t.Fatalf("GetIssueLinkByIssue on unlinked issue = %+v, %v; want nil, nil", got, err)
+	}
+
+	if err := store.DeleteIssueLink("t1"); err != nil {
+		t.Fatalf("DeleteIssueLink: %v", err)
+	}
+
+	got, err = store.GetIssueLinkByThread("t1")
+	if err != nil || got != nil {
+		t.Fatalf("GetIssueLinkByThread after delete = %+v, %v; want nil, nil", got, err)
+	}
+}
+
+func testWebhookDeliveries(t *testing.T, store database.Store) {
+	now := time.Now()
+
+	isNew, err := store.RecordWebhookDelivery("d1", now.Add(-48*time.Hour))
+	if err != nil || !isNew {
+		t.Fatalf("RecordWebhookDelivery = %v, %v; want true, nil", isNew, err)
+	}
+
+	isNew, err = store.RecordWebhookDelivery("d1", now)
+	if err != nil || isNew {
+		t.Fatalf("RecordWebhookDelivery redelivery = %v, %v; want false, nil", isNew, err)
+	}
+
+	if _, err := store.RecordWebhookDelivery("d2", now); err != nil {
+		t.Fatalf("RecordWebhookDelivery: %v", err)
+	}
+
+	deleted, err := store.DeleteWebhookDeliveriesBefore(now.Add(-24 * time.Hour))
+	if err != nil {
+		t.Fatalf("DeleteWebhookDeliveriesBefore: %v", err)
+	}
+	if deleted != 1 {
+		t.Fatalf("DeleteWebhookDeliveriesBefore = %d, want 1", deleted)
+	}
+
+	isNew, err = store.RecordWebhookDelivery("d1", now)
+	if err != nil || !isNew {
+		t.Fatalf("RecordWebhookDelivery after cleanup = %v, %v; want true, nil", isNew, err)
+	}
+
+	if err := store.DeleteWebhookDelivery("d2"); err != nil {
+		t.Fatalf("DeleteWebhookDelivery: %v", err)
+	}
+	isNew, err = store.RecordWebhookDelivery("d2", now)
+	if err != nil || !isNew {
+		t.Fatalf("RecordWebhookDelivery after delete = %v, %v; want true, nil", isNew, err)
+	}
+}
+
+func testWebhookDeliveryTargets(t *testing.T, store database.Store) {
+	now := time.Now()
+
+	if err := store.RecordWebhookDeliveryTarget("d1", "channel:c1", now.Add(-48*time.Hour)); err != nil {
+		t.Fatalf("RecordWebhookDeliveryTarget: %v", err)
+	}
+	// Recording a target twice is not an error.
+	if err := store.RecordWebhookDeliveryTarget("d1", "channel:c1", now.Add(-48*time.Hour)); err != nil {
+		t.Fatalf("RecordWebhookDeliveryTarget again: %v", err)
+	}
+	if err := store.RecordWebhookDeliveryTarget("d2", "channel:c1", now); err != nil {
+		t.Fatalf("RecordWebhookDeliveryTarget: %v", err)
+	}
+
+	for _, tt := range []struct {
+		deliveryID, target string
+		want               bool
+	}{
+		{"d1", "channel:c1", true},
+		{"d1", "channel:c2", false},
+		{"d2", "channel:c1", true},
+		{"d3", "channel:c1", false},
+	} {
+		got, err := store.HasWebhookDeliveryTarget(tt.deliveryID, tt.target)
+		if err != nil {
+			t.Fatalf("HasWebhookDeliveryTarget: %v", err)
+		}
+		if got != tt.want {
+			t.Fatalf("HasWebhookDeliveryTarget(%q, %q) = %v, want %v", tt.deliveryID, tt.target, got, tt.want)
+		}
+	}
+
+	if _, err := store.DeleteWebhookDeliveriesBefore(now.Add(-24 * time.Hour)); err != nil {
+		t.Fatalf("DeleteWebhookDeliveriesBefore: %v", err)
+	}
+	if got, err := store.HasWebhookDeliveryTarget("d1", "channel:c1"); err != nil || got {
+		t.Fatalf("HasWebhookDeliveryTarget after cleanup = %v, %v; want false, nil", got, err)
+	}
+	if got, err := store.HasWebhookDeliveryTarget("d2", "channel:c1"); err != nil || !got {
+		t.Fatalf("HasWebhookDeliveryTarget for recent target = %v, %v; want true, nil", got, err)
+	}
+}
+
+func testOAuthStates(t *testing.T, store database.Store) {
+	if err := store.SaveOAuthState("valid", "1", time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("SaveOAuthState: %v", err)
+	}
+	if err := store.SaveOAuthState("expired", "2", time.Now().Add(-time.Minute)); err != nil {
+		t.Fatalf("SaveOAuthState: %v", err)
+	}
+
+	discordID, err := store.ConsumeOAuthState("valid")
+	if err != nil {
+		t.Fatalf("ConsumeOAuthState: %v", err)
+	}
+	if discordID != "1" {
+		t.Fatalf("ConsumeOAuthState = %q, want 1", discordID)
+	}
+
+	discordID, err = store.ConsumeOAuthState("valid")
+	if err != nil {
+		t.Fatalf("ConsumeOAuthState replay: %v", err)
+	}
+	if discordID != "" {
+		t.Fatalf("ConsumeOAuthState replay = %q, want empty", discordID)
+	}
+
+	discordID, err = store.ConsumeOAuthState("expired")
+	if err != nil {
+		t.Fatalf("ConsumeOAuthState expired: %v", err)
+	}
+	if discordID != "" {
+		t.Fatalf("ConsumeOAuthState expired = %q, want empty", discordID)
+	}
+
+	deleted, err := store.DeleteExpiredOAuthStates()
+	if err != nil {
+		t.Fatalf("DeleteExpiredOAuthStates: %v", err)
+	}
+	if deleted != 1 {
+		t.Fatalf("DeleteExpiredOAuthStates = %d, want 1", deleted)
+	}
+}
+
+func testRotateEncryptionKey(t *testing.T, store database.Store) {
+	if err := store.SaveUser(&database.User{DiscordID: "1", GitHubUsername: "octocat", GitHubToken: "token", GitHubRefreshToken: "refresh"}); err != nil {
+		t.Fatalf("SaveUser: %v", err)
+	}
+
+	// Everything was written under the primary key, so there is nothing to rotate.
+	rotated, err := store.RotateEncryptionKey()
+	if err != nil {
+		t.Fatalf("RotateEncryptionKey: %v", err)
+	}
+	if rotated != 0 {
+		t.Fatalf("RotateEncryptionKey = %d, want 0", rotated)
+	}
+
+	user, err := store.GetUser("1")
+	if err != nil {
+		t.Fatalf("GetUser: %v", err)
+	}
+	if user.GitHubToken != "token" || user.GitHubRefreshToken != "refresh" {
+		t.Fatalf("GetUser after rotation = %+v, want original tokens", user)
+	}
+}
+
+func testSchemaVersion(t *testing.T, store database.Store) {
+	version, err := store.SchemaVersion()
+	if err != nil {
+		t.Fatalf("SchemaVersion: %v", err)
+	}
+	if version < 1 {
+		t.Fatalf("SchemaVersion = %d, want at least 1", version)
+	}
+}
+
+func testMigrationStatus(t *testing.T, store database.Store) {
+	version, pending, err := store.MigrationStatus()
+	if err != nil {
+		t.Fatalf("MigrationStatus: %v", err)
+	}
+	want, err := store.SchemaVersion()
+	if err != nil {
+		t.Fatalf("SchemaVersion: %v", err)
+	}
+	if version != want || len(pending) != 0 {
+		t.Fatalf("MigrationStatus = %d, %v, want %d and no pending migrations", version, pending, want)
+	}
+}
@@ -0,0 +1,199 @@
+package app
+
+import (
+	"context"
+	"crypto"
+	"crypto/rand"
+	"crypto/rsa"
+	"crypto/sha256"
+	"crypto/x509"
+	"encoding/base64"
+	"encoding/json"
+	"encoding/pem"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+	"sync"
+	"time"
+
+	"discord-github-bot/internal/github/client"
+
+	"github.com/google/go-github/v57/github"
+	"golang.org/x/oauth2"
+)
+
+// tokenExpiryMargin is how long before expiry a cached installation token is replaced.
+const tokenExpiryMargin = time.Minute
+
+// InstallationAuth authenticates as a GitHub App installation so the bot can
+// act on its own behalf rather than through a user's personal token.
+// Installation tokens are cached per organization or user account until shortly
+// before they expire.
+type InstallationAuth struct {
+	appID      int64
+	privateKey *rsa.PrivateKey
+	apiURL     string
+	uploadURL  string
+
+	mu              sync.Mutex
+	installationIDs map[string]int64
+	tokens          map[string]*oauth2.Token
+}
+
+func NewInstallationAuth(appID int64, privateKeyPEM []byte, apiURL, uploadURL string) (*InstallationAuth, error) {
+	key, err := parsePrivateKey(privateKeyPEM)
+	if err != nil {
+		return nil, err
+	}
+
+	return &InstallationAuth{
+		appID:           appID,
+		privateKey:      key,
+		apiURL:          apiURL,
+		uploadURL:       uploadURL,
+		installationIDs: make(map[string]int64),
+		tokens:          make(map[string]*oauth2.Token),
+	}, nil
+}
+
+func parsePrivateKey(privateKeyPEM []byte) (*rsa.PrivateKey, error) {
+	block, _ := pem.Decode(privateKeyPEM)
+	if block == nil {
+		return nil, errors.New("failed to decode GitHub App private key PEM")
+	}
+
+	if key, err := x509.ParsePKCS1PrivateKey(block.Bytes); err == nil {
+		return key, nil
+	}
+
+	parsed, err := x509.ParsePKCS8PrivateKey(block.Bytes)
+	if err != nil {
+		return nil, fmt.Errorf("failed to parse GitHub App private key: %w", err)
+	}
+
+	key, ok := parsed.(*rsa.PrivateKey)
+	if !ok {
+		return nil, errors.New("GitHub App private key is not an RSA key")
+	}
+
+	return key, nil
+}
+
+// Client returns a go-github client authenticated as the App installation for
+// org, which may also be a user account.
+func (a *InstallationAuth) Client(org string) (*github.Client, error) {
+	return client.New(oauth2.NewClient(context.Background(), a.TokenSource(org)), a.apiURL, a.uploadURL)
+}
+
+// TokenSource returns a token source yielding installation tokens for org.
+func (a *InstallationAuth) TokenSource(org string) oauth2.TokenSource {
+	return &installationTokenSource{auth: a, org: org}
+}
+
+type installationTokenSource struct {
+	auth *InstallationAuth
+	org  string
+}
+
+func (ts *installationTokenSource) Token() (*oauth2.Token, error) {
+	accessToken, err := ts.auth.Token(ts.org)
+	if err != nil {
+		return nil, err
+	}
+
+	return &oauth2.Token{AccessToken: accessToken, TokenType: "bearer"}, nil
+}
+
+// Token returns an installation access token for org, minting a new one when
+// the cached token is missing or about to expire.
+func (a *InstallationAuth) Token(org string) (string, error) {
+	key := strings.ToLower(org)
+
+	a.mu.Lock()
+	defer a.mu.Unlock()
+
+	if token, ok := a.tokens[key]; ok && time.Until(token.Expiry) > tokenExpiryMargin {
+		return token.AccessToken, nil
+	}
+
+	jwt, err := a.signJWT()
+	if err != nil {
+		return "", err
+	}
+
+	ctx := context.Background()
+	appClient, err := client.New(nil, a.apiURL, a.uploadURL)
+	if err != nil {
+		return "", err
+	}
+	appClient = appClient.WithAuthToken(jwt)
+
+	installationID, ok := a.installationIDs[key]
+	if !ok {
+		installation, err := findInstallation(ctx, appClient, org)
+		if err != nil {
+			return "", fmt.Errorf("failed to find GitHub App installation for %s: %w", org, err)
+		}
+		installationID = installation.GetID()
+		a.installationIDs[key] = installationID
+	}
+
+	installationToken, _, err := appClient.Apps.CreateInstallationToken(ctx, installationID, nil)
+	if err != nil {
+		return "", fmt.Errorf("failed to create installation token for %s: %w", org, err)
+	}
+
+	a.tokens[key] = &oauth2.Token{
+		AccessToken: installationToken.GetToken(),
+		Expiry:      installationToken.GetExpiresAt().Time,
+	}
+
+	return installationToken.GetToken(), nil
+}
+
+// findInstallation looks up the App's installation on owner, which may be an
+// organization or a personal account.
+func findInstallation(ctx context.Context, appClient *github.Client, owner string) (*github.Installation, error) {
+	installation, resp, err := appClient.Apps.FindOrganizationInstallation(ctx, owner)
+	if err == nil {
+		return installation, nil
+	}
+	if resp == nil || resp.StatusCode != http.StatusNotFound {
+		return nil, err
+	}
+
+	installation, _, err = appClient.Apps.FindUserInstallation(ctx, owner)
+	return installation, err
+}
+
+// signJWT creates a short-lived RS256 JWT identifying the App, as required by
+// the App-level endpoints used to look up installations and mint their tokens.
+func (a *InstallationAuth) signJWT() (string, error) {
+	now := time.Now()
+
+	header, err := json.Marshal(map[string]string{"alg": "RS256", "typ": "JWT"})
+	if err != nil {
+		return "", err
+	}
+
+	// Backdate issued-at to allow for clock drift, and stay under GitHub's 10 minute maximum.
+	claims, err := json.Marshal(map[string]interface{}{
+		"iat": now.Add(-time.Minute).Unix(),
+		"exp": now.Add(9 * time.Minute).Unix(),
+		"iss": fmt.Sprintf("%d", a.appID),
+	})
+	if err != nil {
+		return "", err
+	}
+
+	signingInput := base64.RawURLEncoding.EncodeToString(header) + "." + base64.RawURLEncoding.EncodeToString(claims)
+
+	digest := sha256.Sum256([]byte(signingInput))
+	signature, err := rsa.SignPKCS1v15(rand.Reader, a.privateKey, crypto.SHA256, digest[:])
+	if err != nil {
+		return "", fmt.Errorf("failed to sign GitHub App JWT: %w", err)
+	}
+
+	return signingInput + "." + base64.RawURLEncoding.EncodeToString(signature), nil
+}
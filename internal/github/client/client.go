@@ -0,0 +1,21 @@
+package client
+
+import (
+	"net/http"
+	"strings"
+
+	"github.com/google/go-github/v57/github"
+)
+
+// DefaultAPIURL is the REST API root for github.com.
+const DefaultAPIURL = "https://api.github.com/"
+
+// New returns a go-github client for the GitHub instance at apiURL. Any API
+// URL other than github.com's is treated as GitHub Enterprise Server.
+func New(httpClient *http.Client, apiURL, uploadURL string) (*github.Client, error) {
+	if apiURL == "" || strings.TrimSuffix(apiURL, "/") == strings.TrimSuffix(DefaultAPIURL, "/") {
+		return github.NewClient(httpClient), nil
+	}
+
+	return github.NewEnterpriseClient(apiURL, uploadURL, httpClient)
+}
@@ -0,0 +1,66 @@
+package rest
+
+import (
+	"net/url"
+	"strconv"
+	"strings"
+)
+
+// PageLinks are the pagination links from a response's Link header, keyed by
+// rel ("next", "prev", "first", "last"). Each value holds the query parameters
+// that select that page: "before"/"after" cursors for the projectsV2
+// endpoints, "page" for the others.
+type PageLinks map[string]url.Values
+
+// pageParams are the query parameters that identify a page.
+var pageParams = []string{"page", "before", "after"}
+
+func parseLinkHeader(header string) PageLinks {
+	links := PageLinks{}
+
+	for _, link := range strings.Split(header, ",") {
+		segments := strings.Split(strings.TrimSpace(link), ";")
+		if len(segments) < 2 {
+			continue
+		}
+
+		u, err := url.Parse(strings.Trim(strings.TrimSpace(segments[0]), "<>"))
+		if err != nil {
+			continue
+		}
+
+		params := url.Values{}
+		for _, name := range pageParams {
+			if value := u.Query().Get(name); value != "" {
+				params.Set(name, value)
+			}
+		}
+
+		for _, attr := range segments[1:] {
+			if rel, ok := strings.CutPrefix(strings.TrimSpace(attr), "rel="); ok {
+				links[strings.Trim(rel, `"`)] = params
+			}
+		}
+	}
+
+	return links
+}
+
+// listParams builds the query string shared by the list endpoints.
+func listParams(perPage int, query string, page url.Values) string {
+	params := url.Values{}
+	if perPage > 0 {
+		params.Set("per_page", strconv.Itoa(perPage))
+	}
+	if query != "" {
+		params.Set("q", query)
+	}
+	for name, values := range page {
+		params[name] = values
+	}
+
+	if len(params) == 0 {
+		return ""
+	}
+	return "?" + params.Encode()
+}
@@ -0,0 +1,76 @@
+package rest
+
+import (
+	"net/url"
+	"reflect"
+	"testing"
+)
+
+func TestParseLinkHeader(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   PageLinks
+	}{
+		{
+			name:   "empty",
+			header: "",
+			want:   PageLinks{},
+		},
+		{
+			name:   "next only",
+			header: `<https://api.github.com/orgs/acme/projectsV2?per_page=10&after=Y3Vyc29y>; rel="next"`,
+			want:   PageLinks{"next": {"after": {"Y3Vyc29y"}}},
+		},
+		{
+			name:   "prev and last",
+			header: `<https://api.github.com/repositories/1/issues?page=2&per_page=10>; rel="prev", <https://api.github.com/repositories/1/issues?page=7&per_page=10>; rel="last"`,
+			want: PageLinks{
+				"prev": {"page": {"2"}},
+				"last": {"page": {"7"}},
+			},
+		},
+		{
+			name:   "unquoted rel and extra attributes",
+			header: `<https://api.github.com/x?before=YQ>; title="previous"; rel=prev`,
+			want:   PageLinks{"prev": {"before": {"YQ"}}},
+		},
+		{
+			name:   "malformed entries are skipped",
+			header: `garbage, <https://api.github.com/x?page=3>, <%zz>; rel="first", <https://api.github.com/x?page=4>; rel="next"`,
+			want:   PageLinks{"next": {"page": {"4"}}},
+		},
+		{
+			name:   "link without page parameters",
+			header: `<https://api.github.com/x?per_page=10>; rel="first"`,
+			want:   PageLinks{"first": url.Values{}},
+		},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := parseLinkHeader(tt.header); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("parseLinkHeader() = %v, want %v", got, tt.want)
+			}
+		})
+	}
+}
+
+func TestListParams(t *testing.T) {
+	tests := []struct {
+		perPage int
+		query   string
+		page    url.Values
+		want    string
+	}{
+		{0, "", nil, ""},
+		{10, "", nil, "?per_page=10"},
+		{10, "is:open", url.Values{"after": {"YQ"}}, "?after=YQ&per_page=10&q=is%3Aopen"},
+	}
+
+	for _, tt := range tests {
+		if got := listParams(tt.perPage, tt.query, tt.page); got != tt.want {
+			t.Errorf("listParams(%d, %q, %v) = %q, want %q", tt.perPage, tt.query, tt.page, got, tt.want)
+		}
+	}
+}
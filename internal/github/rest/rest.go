@@ -14,14 +14,22 @@ type GitHubRESTClient struct {
 	HTTPClient *http.Client
 }
 
-func NewGitHubRESTClient() *GitHubRESTClient {
+// NewGitHubRESTClient returns a client for the REST API rooted at baseURL,
+// e.g. https://api.github.com or https://ghes.example.com/api/v3.
+func NewGitHubRESTClient(baseURL string) *GitHubRESTClient {
 	return &GitHubRESTClient{
-		BaseURL:    "https://api.github.com",
+		BaseURL:    strings.TrimSuffix(baseURL, "/"),
 		HTTPClient: &http.Client{},
 	}
 }
 
 func (c *GitHubRESTClient) DoRequest(method, path, token string, body []byte) ([]byte, error) {
+	respBody, _, err := c.doRequest(method, path, token, body)
+	return respBody, err
+}
+
+// doRequest is DoRequest but also returns the response headers.
+func (c *GitHubRESTClient) doRequest(method, path, token string, body []byte) ([]byte, http.Header, error) {
 	var reqBody *strings.Reader
 	if body != nil {
 		reqBody = strings.NewReader(string(body))
@@ -35,7 +43,7 @@ func (c *GitHubRESTClient) DoRequest(method, path, token string, body []byte) ([
 		req, err = http.NewRequest(method, fmt.Sprintf("%s%s", c.BaseURL, path), nil)
 	}
 	if err != nil {
-		return nil, fmt.Errorf("failed to create request: %w", err)
+		return nil, nil, fmt.Errorf("failed to create request: %w", err)
 	}
 
 	req.Header.Set("Accept", "application/vnd.github+json")
@@ -47,78 +55,57 @@ func (c *GitHubRESTClient) DoRequest(method, path, token string, body []byte) ([
 
 	resp, err := c.HTTPClient.Do(req)
 	if err != nil {
-		return nil, fmt.Errorf("failed to execute request: %w", err)
+		return nil, nil, fmt.Errorf("failed to execute request: %w", err)
 	}
 	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK && resp.StatusCode != http.StatusCreated {
-		return nil, fmt.Errorf("GitHub API returned status %d: %s", resp.StatusCode, resp.Status)
+		return nil, nil, fmt.Errorf("GitHub API returned status %d: %s", resp.StatusCode, resp.Status)
 	}
 
 	respBody, err := ioutil.ReadAll(resp.Body)
 	if err != nil {
-		return nil, fmt.Errorf("failed to read response body: %w", err)
+		return nil, nil, fmt.Errorf("failed to read response body: %w", err)
 	}
 
-	return respBody, nil
+	return respBody, resp.Header, nil
 }
 
-func (c *GitHubRESTClient) ListProjectItems(org string, projectNumber int, token string, perPage int, query string) (*ProjectsV2ItemsResponse, error) {
-	path := fmt.Sprintf("/orgs/%s/projectsV2/%d/items", org, projectNumber)
-
-	// Add query parameters
-	if perPage > 0 || query != "" {
-		params := url.Values{}
-		if perPage > 0 {
-			params.Add("per_page", fmt.Sprintf("%d", perPage))
-		}
-		if query != "" {
-			params.Add("q", query)
-		}
-		path += "?" + params.Encode()
-	}
+// ListProjectItems returns one page of a project's items. page selects the
+// page using parameters from a previous call's PageLinks; nil means the first page.
+func (c *GitHubRESTClient) ListProjectItems(org string, projectNumber int, token string, perPage int, query string, page url.Values) (*ProjectsV2ItemsResponse, PageLinks, error) {
+	path := fmt.Sprintf("/orgs/%s/projectsV2/%d/items", org, projectNumber) + listParams(perPage, query, page)
 
-	body, err := c.DoRequest(http.MethodGet, path, token, nil)
+	body, header, err := c.doRequest(http.MethodGet, path, token, nil)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	var projectItemsResponse ProjectsV2ItemsResponse
 	err = json.Unmarshal(body, &projectItemsResponse)
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal project items response: %w", err)
+		return nil, nil, fmt.Errorf("failed to unmarshal project items response: %w", err)
 	}
 
-	return &projectItemsResponse, nil
+	return &projectItemsResponse, parseLinkHeader(header.Get("Link")), nil
 }
 
-func (c *GitHubRESTClient) ListProjects(org string, token string, perPage int, query string) (*ProjectsV2Response, error) {
-	path := fmt.Sprintf("/orgs/%s/projects", org)
-
-	// Add query parameters
-	if perPage > 0 || query != "" {
-		params := url.Values{}
-		if perPage > 0 {
-			params.Add("per_page", fmt.Sprintf("%d", perPage))
-		}
-		if query != "" {
-			params.Add("q", query)
-		}
-		path += "?" + params.Encode()
-	}
+// ListProjects returns one page of an organization's projects. page works as in ListProjectItems.
+func (c *GitHubRESTClient) ListProjects(org string, token string, perPage int, query string, page url.Values) (*ProjectsV2Response, PageLinks, error) {
+	path := fmt.Sprintf("/orgs/%s/projects", org) + listParams(perPage, query, page)
 
-	body, err := c.DoRequest(http.MethodGet, path, token, nil)
+	body, header, err := c.doRequest(http.MethodGet, path, token, nil)
 	if err != nil {
-		return nil, err
+		return nil, nil, err
 	}
 
 	var projectsResponse ProjectsV2Response
 	err = json.Unmarshal(body, &projectsResponse)
 	if err != nil {
-		return nil, fmt.Errorf("failed to unmarshal projects response: %w", err)
+		return nil, nil, fmt.Errorf("failed to unmarshal projects response: %w", err)
 	}
 
-	return &projectsResponse, nil
+	return &projectsResponse, parseLinkHeader(header.Get("Link")), nil
 }
 
 func (c *GitHubRESTClient) AddIssueToProject(org string, projectNumber int, issueID int, token string) (*ProjectsV2Item, error) {
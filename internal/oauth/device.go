@@ -0,0 +1,45 @@
+package oauth
+
+import (
+	"context"
+	"fmt"
+
+	"discord-github-bot/internal/database"
+
+	"golang.org/x/oauth2"
+)
+
+// StartDeviceLogin begins an OAuth device flow for discordID. It returns the
+// user code and verification URL to show the user, then polls GitHub in the
+// background until the user approves, denies or the code expires. onComplete
+// is called exactly once with the linked user or the error that ended the flow.
+//
+// Unlike the browser flow this needs no reachable callback URL, but "Enable
+// Device Flow" must be turned on in the GitHub OAuth App settings.
+func (s *Server) StartDeviceLogin(discordID string, onComplete func(*database.User, error)) (*oauth2.DeviceAuthResponse, error) {
+	deviceAuth, err := s.oauthConfig.DeviceAuth(context.Background())
+	if err != nil {
+		return nil, fmt.Errorf("failed to start device flow: %w", err)
+	}
+
+	go func() {
+		ctx, cancel := context.WithDeadline(context.Background(), deviceAuth.Expiry)
+		defer cancel()
+
+		token, err := s.oauthConfig.DeviceAccessToken(ctx, deviceAuth)
+		if err != nil {
+			onComplete(nil, fmt.Errorf("device flow did not complete: %w", err))
+			return
+		}
+
+		user, err := s.saveToken(ctx, discordID, token)
+		if err != nil {
+			onComplete(nil, err)
+			return
+		}
+
+		onComplete(user, nil)
+	}()
+
+	return deviceAuth, nil
+}
@@ -0,0 +1,85 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+	"net/http"
+	"strings"
+)
+
+// ErrTokenInvalid is returned when a stored token has been revoked or can no
+// longer be refreshed, and the user has to run /gh-auth again.
+var ErrTokenInvalid = errors.New("GitHub token is no longer valid, please run /gh-auth again")
+
+// MissingScopesError is returned when a token is valid but lacks scopes the bot requests.
+type MissingScopesError struct {
+	Scopes []string
+}
+
+func (e *MissingScopesError) Error() string {
+	return fmt.Sprintf("GitHub token is missing scopes: %s", strings.Join(e.Scopes, ", "))
+}
+
+// impliedScopes lists broader scopes that satisfy a requested scope.
+var impliedScopes = map[string][]string{
+	"user:email": {"user"},
+	"read:org":   {"write:org", "admin:org"},
+}
+
+// CheckTokenHealth validates discordID's stored token against GitHub. It
+// returns ErrTokenInvalid if the token was revoked, a *MissingScopesError if
+// the granted scopes were reduced, or another error if GitHub could not be
+// reached. A nil error means the token is healthy.
+func (s *Server) CheckTokenHealth(discordID string) error {
+	client, err := s.GetGitHubClient(discordID)
+	if err != nil {
+		return err
+	}
+
+	_, resp, err := client.Users.Get(context.Background(), "")
+	if err != nil {
+		if resp != nil && resp.StatusCode == http.StatusUnauthorized {
+			return ErrTokenInvalid
+		}
+		return err
+	}
+
+	// GitHub App user tokens have no OAuth scopes and omit the header entirely.
+	header, ok := resp.Header[http.CanonicalHeaderKey("X-OAuth-Scopes")]
+	if !ok {
+		return nil
+	}
+
+	if missing := missingScopes(strings.Join(header, ",")); len(missing) > 0 {
+		return &MissingScopesError{Scopes: missing}
+	}
+
+	return nil
+}
+
+func missingScopes(header string) []string {
+	granted := make(map[string]bool)
+	for _, scope := range strings.Split(header, ",") {
+		granted[strings.TrimSpace(scope)] = true
+	}
+
+	var missing []string
+	for _, scope := range Scopes {
+		if granted[scope] || grantedByImplication(granted, scope) {
+			continue
+		}
+		missing = append(missing, scope)
+	}
+
+	return missing
+}
+
+func grantedByImplication(granted map[string]bool, scope string) bool {
+	for _, broader := range impliedScopes[scope] {
+		if granted[broader] {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,38 @@
+package oauth
+
+import (
+	"reflect"
+	"testing"
+)
+
+func TestMissingScopes(t *testing.T) {
+	tests := []struct {
+		name   string
+		header string
+		want   []string
+	}{
+		{"all requested", "repo, user:email, read:org, project", nil},
+		{"no spaces", "repo,user:email,read:org,project", nil},
+		{"broader scopes", "repo, user, admin:org, project", nil},
+		{"write org implies read org", "repo, user:email, write:org, project", nil},
+		{"narrower repo scope", "public_repo, user:email, read:org, project", []string{"repo"}},
+		{"scopes dropped", "repo, user:email", []string{"read:org", "project"}},
+		{"read org does not imply user", "read:org", []string{"repo", "user:email", "project"}},
+		{"no scopes", "", []string{"repo", "user:email", "read:org", "project"}},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			if got := missingScopes(tt.header); !reflect.DeepEqual(got, tt.want) {
+				t.Errorf("missingScopes(%q) = %v, want %v", tt.header, got, tt.want)
+			}
+		})
+	}
+}
+
+func TestMissingScopesError(t *testing.T) {
+	err := &MissingScopesError{Scopes: []string{"read:org", "project"}}
+	if got, want := err.Error(), "GitHub token is missing scopes: read:org, project"; got != want {
+		t.Errorf("Error() = %q, want %q", got, want)
+	}
+}
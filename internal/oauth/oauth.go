@@ -2,40 +2,64 @@ package oauth
 
 import (
 	"context"
+	"crypto/hmac"
 	"crypto/rand"
+	"crypto/sha256"
+	"crypto/subtle"
 	"encoding/base64"
+	"errors"
 	"fmt"
 	"html/template"
 	"log"
 	"net/http"
 	"os"
+	"strconv"
+	"strings"
 	"sync"
 	"time"
 
 	"discord-github-bot/internal/config"
 	"discord-github-bot/internal/database"
+	"discord-github-bot/internal/github/client"
 
 	"github.com/google/go-github/v57/github"
 	"golang.org/x/oauth2"
-	oauth2gh "golang.org/x/oauth2/github"
 )
 
+const (
+	// authLinkTTL is how long a link minted by /gh-auth stays valid.
+	authLinkTTL = 10 * time.Minute
+
+	// stateTTL is how long a pending OAuth callback state stays valid.
+	stateTTL = 10 * time.Minute
+
+	// stateSweepInterval is how often expired states are purged from the database.
+	stateSweepInterval = time.Minute
+)
+
+// Scopes are the OAuth scopes requested from GitHub and expected on every stored token.
+var Scopes = []string{"repo", "user:email", "read:org", "project"}
+
 type Server struct {
 	config      *config.Config
-	db          *database.Database
+	db          database.Store
 	oauthConfig *oauth2.Config
-	states      map[string]string
-	statesMu    sync.RWMutex
+	linkKey     []byte
+	refreshMu   sync.Mutex
 	tmpl        *template.Template
 }
 
-func NewServer(cfg *config.Config, db *database.Database) *Server {
+func NewServer(cfg *config.Config, db database.Store) *Server {
 	oauthConfig := &oauth2.Config{
 		ClientID:     cfg.GitHubClientID,
 		ClientSecret: cfg.GitHubClientSecret,
 		RedirectURL:  cfg.GitHubRedirectURL,
-		Scopes:       []string{"repo", "user:email", "read:org", "project"},
-		Endpoint:     oauth2gh.Endpoint,
+		Scopes:       Scopes,
+		Endpoint: oauth2.Endpoint{
+			AuthURL:       cfg.GitHubURL + "/login/oauth/authorize",
+			TokenURL:      cfg.GitHubURL + "/login/oauth/access_token",
+			DeviceAuthURL: cfg.GitHubURL + "/login/device/code",
+		},
 	}
 
 	tmpl, err := template.ParseFiles("templates/success.html")
@@ -47,34 +71,120 @@ func NewServer(cfg *config.Config, db *database.Database) *Server {
 		config:      cfg,
 		db:          db,
 		oauthConfig: oauthConfig,
-		states:      make(map[string]string),
+		linkKey:     deriveLinkKey(cfg.EncryptionKey),
 		tmpl:        tmpl,
 	}
 }
 
+// deriveLinkKey derives the HMAC key used to sign auth links from the
+// encryption key, so the same secret is never used for two purposes.
+func deriveLinkKey(encryptionKey []byte) []byte {
+	mac := hmac.New(sha256.New, encryptionKey)
+	mac.Write([]byte("discord-github-bot/auth-link"))
+	return mac.Sum(nil)
+}
+
+// Handle registers an additional handler, such as the webhook receiver, on the
+// server's listener. It must be called before Start.
+func (s *Server) Handle(pattern string, handler http.Handler) {
+	http.Handle(pattern, handler)
+}
+
 func (s *Server) Start() error {
 	http.HandleFunc("/", s.handleIndex)
 	http.HandleFunc("/auth", s.handleAuth)
 	http.HandleFunc("/callback", s.handleCallback)
 
+	go s.sweepStates()
+
 	addr := fmt.Sprintf("%s:%s", s.config.OAuthServerHost, s.config.OAuthServerPort)
 	return http.ListenAndServe(addr, nil)
 }
 
-func (s *Server) GenerateAuthURL(discordID string) string {
+// sweepStates periodically removes expired auth link nonces and OAuth states.
+func (s *Server) sweepStates() {
+	ticker := time.NewTicker(stateSweepInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if _, err := s.db.DeleteExpiredOAuthStates(); err != nil {
+			log.Printf("Failed to delete expired OAuth states: %v", err)
+		}
+	}
+}
+
+func (s *Server) GenerateAuthURL(discordID string) (string, error) {
 	state := s.generateState()
-	s.statesMu.Lock()
-	s.states[state] = discordID
-	s.statesMu.Unlock()
+	if err := s.db.SaveOAuthState(state, discordID, time.Now().Add(stateTTL)); err != nil {
+		return "", err
+	}
 
-	go func() {
-		time.Sleep(10 * time.Minute)
-		s.statesMu.Lock()
-		delete(s.states, state)
-		s.statesMu.Unlock()
-	}()
+	return s.oauthConfig.AuthCodeURL(state, oauth2.AccessTypeOnline, oauth2.SetAuthURLParam("prompt", "select_account")), nil
+}
 
-	return s.oauthConfig.AuthCodeURL(state, oauth2.AccessTypeOnline, oauth2.SetAuthURLParam("prompt", "select_account"))
+// GenerateAuthLink returns a signed, single-use link that starts the OAuth
+// flow for the given Discord user. The link expires after authLinkTTL.
+func (s *Server) GenerateAuthLink(discordID string) (string, error) {
+	nonce := s.generateState()
+	expiresAt := time.Now().Add(authLinkTTL)
+
+	if err := s.db.SaveOAuthState(nonce, discordID, expiresAt); err != nil {
+		return "", err
+	}
+
+	payload := fmt.Sprintf("%s:%s:%d", discordID, nonce, expiresAt.Unix())
+	token := base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + s.signLink(payload)
+
+	return fmt.Sprintf("%s/auth?token=%s", s.config.PublicURL, token), nil
+}
+
+// consumeAuthLink verifies a token produced by GenerateAuthLink and returns
+// the Discord ID it was minted for. A token can only be consumed once.
+func (s *Server) consumeAuthLink(token string) (string, error) {
+	encodedPayload, signature, found := strings.Cut(token, ".")
+	if !found {
+		return "", errors.New("malformed token")
+	}
+
+	rawPayload, err := base64.RawURLEncoding.DecodeString(encodedPayload)
+	if err != nil {
+		return "", errors.New("malformed token")
+	}
+	payload := string(rawPayload)
+
+	if subtle.ConstantTimeCompare([]byte(signature), []byte(s.signLink(payload))) != 1 {
+		return "", errors.New("invalid signature")
+	}
+
+	parts := strings.Split(payload, ":")
+	if len(parts) != 3 {
+		return "", errors.New("malformed token")
+	}
+	discordID, nonce := parts[0], parts[1]
+
+	expiry, err := strconv.ParseInt(parts[2], 10, 64)
+	if err != nil {
+		return "", errors.New("malformed token")
+	}
+	if time.Now().Unix() > expiry {
+		return "", errors.New("token expired")
+	}
+
+	owner, err := s.db.ConsumeOAuthState(nonce)
+	if err != nil {
+		return "", err
+	}
+	if owner == "" || owner != discordID {
+		return "", errors.New("token already used or unknown")
+	}
+
+	return discordID, nil
+}
+
+func (s *Server) signLink(payload string) string {
+	mac := hmac.New(sha256.New, s.linkKey)
+	mac.Write([]byte(payload))
+	return base64.RawURLEncoding.EncodeToString(mac.Sum(nil))
 }
 
 func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
@@ -121,13 +231,26 @@ func (s *Server) handleIndex(w http.ResponseWriter, r *http.Request) {
 }
 
 func (s *Server) handleAuth(w http.ResponseWriter, r *http.Request) {
-	discordID := r.URL.Query().Get("discord_id")
-	if discordID == "" {
-		http.Error(w, "Missing discord_id parameter", http.StatusBadRequest)
+	token := r.URL.Query().Get("token")
+	if token == "" {
+		http.Error(w, "Missing token parameter", http.StatusBadRequest)
+		return
+	}
+
+	discordID, err := s.consumeAuthLink(token)
+	if err != nil {
+		log.Printf("Rejected auth link: %v", err)
+		http.Error(w, "Invalid or expired authentication link. Run /gh-auth again.", http.StatusBadRequest)
+		return
+	}
+
+	authURL, err := s.GenerateAuthURL(discordID)
+	if err != nil {
+		log.Printf("Failed to save OAuth state: %v", err)
+		http.Error(w, "Failed to start authentication", http.StatusInternalServerError)
 		return
 	}
 
-	authURL := s.GenerateAuthURL(discordID)
 	http.Redirect(w, r, authURL, http.StatusTemporaryRedirect)
 }
 
@@ -140,14 +263,14 @@ func (s *Server) handleCallback(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	s.statesMu.Lock()
-	discordID, exists := s.states[state]
-	if exists {
-		delete(s.states, state)
+	discordID, err := s.db.ConsumeOAuthState(state)
+	if err != nil {
+		log.Printf("Failed to consume OAuth state: %v", err)
+		http.Error(w, "Failed to validate state parameter", http.StatusInternalServerError)
+		return
 	}
-	s.statesMu.Unlock()
 
-	if !exists {
+	if discordID == "" {
 		http.Error(w, "Invalid or expired state parameter", http.StatusBadRequest)
 		return
 	}
@@ -160,21 +283,8 @@ func (s *Server) handleCallback(w http.ResponseWriter, r *http.Request) {
 		return
 	}
 
-	client := github.NewClient(s.oauthConfig.Client(ctx, token))
-	ghUser, _, err := client.Users.Get(ctx, "")
+	user, err := s.saveToken(ctx, discordID, token)
 	if err != nil {
-		log.Printf("Failed to get GitHub user: %v", err)
-		http.Error(w, "Failed to get GitHub user information", http.StatusInternalServerError)
-		return
-	}
-
-	user := &database.User{
-		DiscordID:      discordID,
-		GitHubUsername: ghUser.GetLogin(),
-		GitHubToken:    token.AccessToken,
-	}
-
-	if err := s.db.SaveUser(user); err != nil {
 		log.Printf("Failed to save user: %v", err)
 		http.Error(w, "Failed to save user information", http.StatusInternalServerError)
 		return
@@ -184,7 +294,7 @@ func (s *Server) handleCallback(w http.ResponseWriter, r *http.Request) {
 	data := struct {
 		GitHubUsername string
 	}{
-		GitHubUsername: ghUser.GetLogin(),
+		GitHubUsername: user.GitHubUsername,
 	}
 
 	if err := s.tmpl.Execute(w, data); err != nil {
@@ -193,6 +303,33 @@ func (s *Server) handleCallback(w http.ResponseWriter, r *http.Request) {
 	}
 }
 
+// saveToken looks up the GitHub account that owns token and links it to discordID.
+func (s *Server) saveToken(ctx context.Context, discordID string, token *oauth2.Token) (*database.User, error) {
+	client, err := s.newGitHubClient(s.oauthConfig.Client(ctx, token))
+	if err != nil {
+		return nil, err
+	}
+
+	ghUser, _, err := client.Users.Get(ctx, "")
+	if err != nil {
+		return nil, fmt.Errorf("failed to get GitHub user: %w", err)
+	}
+
+	user := &database.User{
+		DiscordID:          discordID,
+		GitHubUsername:     ghUser.GetLogin(),
+		GitHubToken:        token.AccessToken,
+		GitHubRefreshToken: token.RefreshToken,
+		TokenExpiry:        token.Expiry,
+	}
+
+	if err := s.db.SaveUser(user); err != nil {
+		return nil, err
+	}
+
+	return user, nil
+}
+
 func (s *Server) generateState() string {
 	b := make([]byte, 32)
 	rand.Read(b)
@@ -200,33 +337,65 @@ func (s *Server) generateState() string {
 }
 
 func (s *Server) GetGitHubClient(discordID string) (*github.Client, error) {
-	user, err := s.db.GetUser(discordID)
+	ts, err := s.tokenSource(discordID)
 	if err != nil {
 		return nil, err
 	}
 
-	if user == nil {
-		return nil, fmt.Errorf("user not authenticated with GitHub")
-	}
+	tc := oauth2.NewClient(context.Background(), ts)
 
-	ctx := context.Background()
-	ts := oauth2.StaticTokenSource(
-		&oauth2.Token{AccessToken: user.GitHubToken},
-	)
-	tc := oauth2.NewClient(ctx, ts)
+	return s.newGitHubClient(tc)
+}
 
-	return github.NewClient(tc), nil
+// newGitHubClient returns a go-github client for the configured GitHub instance.
+func (s *Server) newGitHubClient(httpClient *http.Client) (*github.Client, error) {
+	return client.New(httpClient, s.config.GitHubAPIURL, s.config.GitHubUploadURL)
 }
 
 func (s *Server) GetGitHubToken(discordID string) (string, error) {
-	user, err := s.db.GetUser(discordID)
+	ts, err := s.tokenSource(discordID)
+	if err != nil {
+		return "", err
+	}
+
+	token, err := ts.Token()
 	if err != nil {
 		return "", err
 	}
 
+	return token.AccessToken, nil
+}
+
+// RevokeGrant revokes the GitHub authorization for discordID's stored token,
+// invalidating it and every other token GitHub issued for this app to that user.
+// It does not remove the user from the database.
+func (s *Server) RevokeGrant(discordID string) error {
+	user, err := s.db.GetUser(discordID)
+	if err != nil {
+		return err
+	}
+
 	if user == nil {
-		return "", fmt.Errorf("user not authenticated with GitHub")
+		return fmt.Errorf("user not authenticated with GitHub")
+	}
+
+	tp := &github.BasicAuthTransport{
+		Username: s.config.GitHubClientID,
+		Password: s.config.GitHubClientSecret,
+	}
+	ghClient, err := s.newGitHubClient(tp.Client())
+	if err != nil {
+		return err
+	}
+
+	resp, err := ghClient.Authorizations.DeleteGrant(context.Background(), s.config.GitHubClientID, user.GitHubToken)
+	if err != nil {
+		// A 404 means GitHub no longer knows the token, so there is nothing left to revoke.
+		if resp != nil && resp.StatusCode == http.StatusNotFound {
+			return nil
+		}
+		return err
 	}
 
-	return user.GitHubToken, nil
+	return nil
 }
@@ -0,0 +1,106 @@
+package oauth
+
+import (
+	"encoding/base64"
+	"fmt"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"discord-github-bot/internal/config"
+	"discord-github-bot/internal/database"
+	"discord-github-bot/internal/database/storetest"
+)
+
+func newTestServer(t *testing.T, encryptionKey string) *Server {
+	db, err := database.New(filepath.Join(t.TempDir(), "bot.db"), storetest.Keys)
+	if err != nil {
+		t.Fatalf("database.New: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return &Server{
+		config:  &config.Config{PublicURL: "https://bot.example"},
+		db:      db,
+		linkKey: deriveLinkKey([]byte(encryptionKey)),
+	}
+}
+
+// signedToken builds an auth link token for payload signed by s, as
+// GenerateAuthLink does, so tests can mint tokens it never would.
+func signedToken(s *Server, discordID, nonce string, expiresAt time.Time) string {
+	payload := fmt.Sprintf("%s:%s:%d", discordID, nonce, expiresAt.Unix())
+	return base64.RawURLEncoding.EncodeToString([]byte(payload)) + "." + s.signLink(payload)
+}
+
+func authLinkToken(t *testing.T, s *Server, discordID string) string {
+	link, err := s.GenerateAuthLink(discordID)
+	if err != nil {
+		t.Fatalf("GenerateAuthLink: %v", err)
+	}
+	_, token, found := strings.Cut(link, "/auth?token=")
+	if !found {
+		t.Fatalf("GenerateAuthLink = %q, want a /auth?token= link", link)
+	}
+	return token
+}
+
+func TestConsumeAuthLink(t *testing.T) {
+	s := newTestServer(t, "key")
+	token := authLinkToken(t, s, "123")
+
+	discordID, err := s.consumeAuthLink(token)
+	if err != nil || discordID != "123" {
+		t.Fatalf("consumeAuthLink = %q, %v; want 123, nil", discordID, err)
+	}
+
+	if _, err := s.consumeAuthLink(token); err == nil {
+		t.Fatal("consumeAuthLink accepted a replayed link")
+	}
+}
+
+func TestConsumeAuthLinkRejects(t *testing.T) {
+	s := newTestServer(t, "key")
+	other := newTestServer(t, "other key")
+
+	if err := s.db.SaveOAuthState("nonce-1", "123", time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("SaveOAuthState: %v", err)
+	}
+	if err := s.db.SaveOAuthState("nonce-2", "123", time.Now().Add(time.Minute)); err != nil {
+		t.Fatalf("SaveOAuthState: %v", err)
+	}
+
+	valid := signedToken(s, "123", "nonce-1", time.Now().Add(time.Minute))
+	encodedPayload, signature, _ := strings.Cut(valid, ".")
+	forgedPayload := base64.RawURLEncoding.EncodeToString([]byte(fmt.Sprintf("456:nonce-1:%d", time.Now().Add(time.Minute).Unix())))
+
+	tests := []struct {
+		name  string
+		token string
+		want  string
+	}{
+		{"no signature", encodedPayload, "malformed token"},
+		{"payload not base64", "!!!." + signature, "malformed token"},
+		{"bad signature", encodedPayload + ".AAAA", "invalid signature"},
+		{"payload changed", forgedPayload + "." + signature, "invalid signature"},
+		{"signed with another key", signedToken(other, "123", "nonce-1", time.Now().Add(time.Minute)), "invalid signature"},
+		{"expired", signedToken(s, "123", "nonce-1", time.Now().Add(-time.Second)), "token expired"},
+		{"unknown nonce", signedToken(s, "123", "nonce-unknown", time.Now().Add(time.Minute)), "token already used or unknown"},
+		{"nonce minted for another user", signedToken(s, "456", "nonce-2", time.Now().Add(time.Minute)), "token already used or unknown"},
+	}
+
+	for _, tt := range tests {
+		t.Run(tt.name, func(t *testing.T) {
+			discordID, err := s.consumeAuthLink(tt.token)
+			if err == nil || err.Error() != tt.want {
+				t.Fatalf("consumeAuthLink = %q, %v; want error %q", discordID, err, tt.want)
+			}
+		})
+	}
+
+	// None of the rejected tokens used up the legitimate link.
+	if discordID, err := s.consumeAuthLink(valid); err != nil || discordID != "123" {
+		t.Fatalf("consumeAuthLink of the valid token = %q, %v; want 123, nil", discordID, err)
+	}
+}
@@ -0,0 +1,97 @@
+package oauth
+
+import (
+	"context"
+	"errors"
+	"fmt"
+
+	"discord-github-bot/internal/database"
+
+	"golang.org/x/oauth2"
+)
+
+// userTokenSource yields a user's GitHub token, refreshing it with the stored
+// refresh token once it expires and writing the rotated token back to the
+// database. Tokens from classic OAuth Apps have no expiry and are returned as-is.
+type userTokenSource struct {
+	server    *Server
+	discordID string
+}
+
+// tokenSource returns a caching token source for the given Discord user.
+func (s *Server) tokenSource(discordID string) (oauth2.TokenSource, error) {
+	user, err := s.db.GetUser(discordID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user == nil {
+		return nil, fmt.Errorf("user not authenticated with GitHub")
+	}
+
+	if user.TokenStale {
+		return nil, ErrTokenInvalid
+	}
+
+	src := &userTokenSource{server: s, discordID: discordID}
+	return oauth2.ReuseTokenSource(userToken(user), src), nil
+}
+
+func (ts *userTokenSource) Token() (*oauth2.Token, error) {
+	// GitHub refresh tokens are single-use, so concurrent refreshes for the
+	// same user would invalidate each other. Serialize them and re-read the
+	// user so a token rotated by another caller is picked up.
+	ts.server.refreshMu.Lock()
+	defer ts.server.refreshMu.Unlock()
+
+	user, err := ts.server.db.GetUser(ts.discordID)
+	if err != nil {
+		return nil, err
+	}
+
+	if user == nil {
+		return nil, fmt.Errorf("user not authenticated with GitHub")
+	}
+
+	current := userToken(user)
+	if current.Valid() {
+		return current, nil
+	}
+
+	if user.GitHubRefreshToken == "" {
+		return nil, ErrTokenInvalid
+	}
+
+	refreshed, err := ts.server.oauthConfig.TokenSource(context.Background(), current).Token()
+	if err != nil {
+		// GitHub rejected the refresh token itself, so only re-authenticating will help.
+		var retrieveErr *oauth2.RetrieveError
+		if errors.As(err, &retrieveErr) {
+			return nil, fmt.Errorf("%w: %v", ErrTokenInvalid, err)
+		}
+		return nil, fmt.Errorf("failed to refresh GitHub token: %w", err)
+	}
+
+	updated := &database.User{
+		DiscordID:          user.DiscordID,
+		GitHubUsername:     user.GitHubUsername,
+		GitHubToken:        refreshed.AccessToken,
+		GitHubRefreshToken: refreshed.RefreshToken,
+		TokenExpiry:        refreshed.Expiry,
+	}
+
+	if err := ts.server.db.SaveUser(updated); err != nil {
+		return nil, fmt.Errorf("failed to save refreshed GitHub token: %w", err)
+	}
+
+	return refreshed, nil
+}
+
+func userToken(user *database.User) *oauth2.Token {
+	return &oauth2.Token{
+		AccessToken:  user.GitHubToken,
+		RefreshToken: user.GitHubRefreshToken,
+		Expiry:       user.TokenExpiry,
+		TokenType:    "bearer",
+	}
+}
@@ -0,0 +1,160 @@
+// Package webhook receives GitHub webhook deliveries and hands verified,
+// de-duplicated events to an EventHandler.
+package webhook
+
+import (
+	"log"
+	"net/http"
+	"sync"
+	"time"
+
+	"discord-github-bot/internal/database"
+
+	"github.com/google/go-github/v57/github"
+)
+
+const (
+	// deliveryRetention is how long delivery IDs are remembered. GitHub only
+	// allows redelivering recent deliveries, so older IDs can't come back.
+	deliveryRetention = 7 * 24 * time.Hour
+
+	// deliverySweepInterval is how often expired delivery IDs are purged.
+	deliverySweepInterval = time.Hour
+)
+
+// EventHandler processes a parsed webhook event, such as *github.IssuesEvent.
+// eventType is the X-GitHub-Event header value. Each place the event is posted
+// to should go through delivery.Deliver, and an error returned if any failed.
+type EventHandler interface {
+	HandleGitHubEvent(delivery *Delivery, eventType string, event interface{}) error
+}
+
+// Delivery is one webhook delivery being handled. It remembers which targets
+// the delivery reached, so when a partly failed delivery is redelivered only
+// the targets that failed get the event.
+type Delivery struct {
+	ID string
+	db database.Store
+}
+
+// Deliver calls send to post the delivery's event to target, such as a
+// channel, unless an earlier attempt at this delivery already did.
+func (d *Delivery) Deliver(target string, send func() error) error {
+	if d.ID == "" {
+		return send()
+	}
+
+	delivered, err := d.db.HasWebhookDeliveryTarget(d.ID, target)
+	if err != nil {
+		return err
+	}
+	if delivered {
+		return nil
+	}
+
+	if err := send(); err != nil {
+		return err
+	}
+
+	if err := d.db.RecordWebhookDeliveryTarget(d.ID, target, time.Now()); err != nil {
+		log.Printf("Failed to record webhook delivery %s to %s: %v", d.ID, target, err)
+	}
+	return nil
+}
+
+// Handler is the http.Handler for the /webhook endpoint.
+type Handler struct {
+	secret []byte
+	db     database.Store
+	events EventHandler
+
+	// handling tracks events still being handled after their response.
+	handling sync.WaitGroup
+}
+
+func NewHandler(secret string, db database.Store, events EventHandler) *Handler {
+	return &Handler{
+		secret: []byte(secret),
+		db:     db,
+		events: events,
+	}
+}
+
+func (h *Handler) ServeHTTP(w http.ResponseWriter, r *http.Request) {
+	if r.Method != http.MethodPost {
+		http.Error(w, "Method not allowed", http.StatusMethodNotAllowed)
+		return
+	}
+
+	payload, err := github.ValidatePayload(r, h.secret)
+	if err != nil {
+		log.Printf("Rejected webhook delivery: %v", err)
+		http.Error(w, "Invalid signature", http.StatusUnauthorized)
+		return
+	}
+
+	eventType := github.WebHookType(r)
+	deliveryID := github.DeliveryID(r)
+
+	if eventType == "ping" {
+		w.WriteHeader(http.StatusOK)
+		w.Write([]byte("pong"))
+		return
+	}
+
+	event, err := github.ParseWebHook(eventType, payload)
+	if err != nil {
+		// Unsupported event types are acknowledged so GitHub doesn't report failures.
+		log.Printf("Ignoring webhook event %q (delivery %s): %v", eventType, deliveryID, err)
+		w.WriteHeader(http.StatusAccepted)
+		return
+	}
+
+	if deliveryID != "" {
+		isNew, err := h.db.RecordWebhookDelivery(deliveryID, time.Now())
+		if err != nil {
+			log.Printf("Failed to record webhook delivery %s: %v", deliveryID, err)
+			http.Error(w, "Failed to record delivery", http.StatusInternalServerError)
+			return
+		}
+		if !isNew {
+			log.Printf("Ignoring duplicate webhook delivery %s", deliveryID)
+			w.WriteHeader(http.StatusOK)
+			return
+		}
+	}
+
+	// GitHub times out after 10 seconds, so post to Discord after responding.
+	// A delivery that fails is forgotten again, so redelivering it from
+	// GitHub's webhook settings retries the targets it didn't reach instead of
+	// being ignored as a duplicate.
+	delivery := &Delivery{ID: deliveryID, db: h.db}
+	h.handling.Add(1)
+	go func() {
+		defer h.handling.Done()
+		if err := h.events.HandleGitHubEvent(delivery, eventType, event); err != nil {
+			log.Printf("Failed to handle webhook event %q (delivery %s): %v", eventType, deliveryID, err)
+			if deliveryID == "" {
+				return
+			}
+			if err := h.db.DeleteWebhookDelivery(deliveryID); err != nil {
+				log.Printf("Failed to forget webhook delivery %s: %v", deliveryID, err)
+			}
+		}
+	}()
+
+	w.WriteHeader(http.StatusAccepted)
+}
+
+// SweepDeliveries periodically forgets delivery IDs older than deliveryRetention.
+// It never returns and should be run in its own goroutine.
+func (h *Handler) SweepDeliveries() {
+	ticker := time.NewTicker(deliverySweepInterval)
+	defer ticker.Stop()
+
+	for range ticker.C {
+		if _, err := h.db.DeleteWebhookDeliveriesBefore(time.Now().Add(-deliveryRetention)); err != nil {
+			log.Printf("Failed to delete old webhook deliveries: %v", err)
+		}
+	}
+}
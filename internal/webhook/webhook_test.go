@@ -0,0 +1,124 @@
+package webhook
+
+import (
+	"crypto/hmac"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
+	"net/http"
+	"net/http/httptest"
+	"path/filepath"
+	"strings"
+	"sync"
+	"testing"
+
+	"discord-github-bot/internal/database"
+	"discord-github-bot/internal/database/storetest"
+)
+
+const testSecret = "secret"
+
+// fakeEvents posts each event to targets, failing the targets in failing.
+type fakeEvents struct {
+	mu      sync.Mutex
+	targets []string
+	failing map[string]bool
+	posts   map[string]int
+}
+
+func (f *fakeEvents) HandleGitHubEvent(delivery *Delivery, eventType string, event interface{}) error {
+	var errs []error
+	for _, target := range f.targets {
+		err := delivery.Deliver(target, func() error {
+			f.mu.Lock()
+			defer f.mu.Unlock()
+			if f.failing[target] {
+				return errors.New("missing access")
+			}
+			f.posts[target]++
+			return nil
+		})
+		if err != nil {
+			errs = append(errs, err)
+		}
+	}
+	return errors.Join(errs...)
+}
+
+func newTestHandler(t *testing.T, events EventHandler) *Handler {
+	db, err := database.New(filepath.Join(t.TempDir(), "bot.db"), storetest.Keys)
+	if err != nil {
+		t.Fatalf("database.New: %v", err)
+	}
+	t.Cleanup(func() { db.Close() })
+
+	return NewHandler(testSecret, db, events)
+}
+
+// deliver sends a signed issues event with deliveryID and waits for it to be handled.
+func deliver(t *testing.T, h *Handler, deliveryID string) int {
+	payload := `{"action":"opened","issue":{"number":1},"repository":{"full_name":"owner/repo"}}`
+	mac := hmac.New(sha256.New, []byte(testSecret))
+	mac.Write([]byte(payload))
+
+	req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(payload))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Event", "issues")
+	req.Header.Set("X-GitHub-Delivery", deliveryID)
+	req.Header.Set("X-Hub-Signature-256", "sha256="+hex.EncodeToString(mac.Sum(nil)))
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	h.handling.Wait()
+
+	return rec.Code
+}
+
+func TestRedeliveryAfterPartialFailure(t *testing.T) {
+	events := &fakeEvents{
+		targets: []string{"channel:ok", "channel:broken"},
+		failing: map[string]bool{"channel:broken": true},
+		posts:   make(map[string]int),
+	}
+	h := newTestHandler(t, events)
+
+	if code := deliver(t, h, "d1"); code != http.StatusAccepted {
+		t.Fatalf("first delivery status = %d, want %d", code, http.StatusAccepted)
+	}
+
+	// The channel is fixed and the delivery redelivered from GitHub.
+	events.failing = nil
+	if code := deliver(t, h, "d1"); code != http.StatusAccepted {
+		t.Fatalf("redelivery status = %d, want %d", code, http.StatusAccepted)
+	}
+
+	if events.posts["channel:ok"] != 1 || events.posts["channel:broken"] != 1 {
+		t.Fatalf("posts = %v, want each channel posted to once", events.posts)
+	}
+
+	// Once every target is reached, further redeliveries are duplicates.
+	if code := deliver(t, h, "d1"); code != http.StatusOK {
+		t.Fatalf("duplicate delivery status = %d, want %d", code, http.StatusOK)
+	}
+	if events.posts["channel:ok"] != 1 || events.posts["channel:broken"] != 1 {
+		t.Fatalf("posts after duplicate = %v, want each channel posted to once", events.posts)
+	}
+}
+
+func TestRejectsInvalidSignature(t *testing.T) {
+	events := &fakeEvents{targets: []string{"channel:ok"}, posts: make(map[string]int)}
+	h := newTestHandler(t, events)
+
+	req := httptest.NewRequest(http.MethodPost, "/webhook", strings.NewReader(`{}`))
+	req.Header.Set("Content-Type", "application/json")
+	req.Header.Set("X-GitHub-Event", "issues")
+	req.Header.Set("X-Hub-Signature-256", "sha256=00")
+
+	rec := httptest.NewRecorder()
+	h.ServeHTTP(rec, req)
+	h.handling.Wait()
+
+	if rec.Code != http.StatusUnauthorized || len(events.posts) != 0 {
+		t.Fatalf("status = %d, posts = %v; want %d and nothing posted", rec.Code, events.posts, http.StatusUnauthorized)
+	}
+}
@@ -1,6 +1,7 @@
 package main
 
 import (
+	"fmt"
 	"log"
 	"os"
 	"os/signal"
@@ -9,7 +10,9 @@ import (
 	"discord-github-bot/internal/bot"
 	"discord-github-bot/internal/config"
 	"discord-github-bot/internal/database"
+	"discord-github-bot/internal/github/app"
 	"discord-github-bot/internal/oauth"
+	"discord-github-bot/internal/webhook"
 
 	"github.com/joho/godotenv"
 )
@@ -24,27 +27,91 @@ func main() {
 		log.Fatalf("Failed to load configuration: %v", err)
 	}
 
-	db, err := database.New(cfg.DatabasePath, cfg.EncryptionKey)
+	keys := database.EncryptionKeys{
+		PrimaryID: cfg.EncryptionKeyID,
+		Keys:      cfg.DecryptionKeys,
+	}
+
+	// Report the schema as found, before opening the database applies migrations.
+	if len(os.Args) > 1 && os.Args[1] == "schema-version" {
+		if err := printSchemaVersion(cfg, keys); err != nil {
+			log.Fatalf("Failed to read database schema version: %v", err)
+		}
+		return
+	}
+
+	var db database.Store
+	if cfg.DatabaseURL != "" {
+		log.Println("Using PostgreSQL database")
+		db, err = database.NewPostgres(cfg.DatabaseURL, keys)
+	} else {
+		db, err = database.New(cfg.DatabasePath, keys)
+	}
 	if err != nil {
 		log.Fatalf("Failed to initialize database: %v", err)
 	}
 	defer db.Close()
 
+	schemaVersion, err := db.SchemaVersion()
+	if err != nil {
+		log.Fatalf("Failed to read database schema version: %v", err)
+	}
+
+	if len(os.Args) > 1 {
+		switch os.Args[1] {
+		case "rotate-keys":
+			rotated, err := db.RotateEncryptionKey()
+			if err != nil {
+				log.Fatalf("Failed to rotate encryption key: %v", err)
+			}
+			log.Printf("Re-encrypted tokens for %d users with key %q", rotated, cfg.EncryptionKeyID)
+		default:
+			log.Fatalf("Unknown command %q (available: rotate-keys, schema-version)", os.Args[1])
+		}
+		return
+	}
+
+	log.Printf("Database schema at version %d", schemaVersion)
+
 	oauthServer := oauth.NewServer(cfg, db)
-	go func() {
-		log.Printf("Starting OAuth server...")
-		log.Printf("  Local address:  http://%s:%s", cfg.OAuthServerHost, cfg.OAuthServerPort)
-		log.Printf("  Public URL:     %s", cfg.PublicURL)
-		if err := oauthServer.Start(); err != nil {
-			log.Fatalf("OAuth server error: %v", err)
+
+	var githubApp *app.InstallationAuth
+	if cfg.GitHubAppID != 0 {
+		githubApp, err = app.NewInstallationAuth(cfg.GitHubAppID, cfg.GitHubAppPrivateKey, cfg.GitHubAPIURL, cfg.GitHubUploadURL)
+		if err != nil {
+			log.Fatalf("Failed to initialize GitHub App authentication: %v", err)
 		}
-	}()
+		log.Printf("GitHub App installation authentication enabled (app ID %d)", cfg.GitHubAppID)
+	}
 
-	discordBot, err := bot.New(cfg, db, oauthServer)
+	discordBot, err := bot.New(cfg, db, oauthServer, githubApp)
 	if err != nil {
 		log.Fatalf("Failed to create Discord bot: %v", err)
 	}
 
+	if cfg.OAuthServerEnabled {
+		if cfg.GitHubWebhookSecret != "" {
+			webhookHandler := webhook.NewHandler(cfg.GitHubWebhookSecret, db, discordBot)
+			oauthServer.Handle("/webhook", webhookHandler)
+			go webhookHandler.SweepDeliveries()
+			log.Printf("GitHub webhooks enabled at %s/webhook", cfg.PublicURL)
+		}
+
+		go func() {
+			log.Printf("Starting OAuth server...")
+			log.Printf("  Local address:  http://%s:%s", cfg.OAuthServerHost, cfg.OAuthServerPort)
+			log.Printf("  Public URL:     %s", cfg.PublicURL)
+			if err := oauthServer.Start(); err != nil {
+				log.Fatalf("OAuth server error: %v", err)
+			}
+		}()
+	} else {
+		log.Println("OAuth server disabled, users must authenticate with the device flow")
+		if cfg.GitHubWebhookSecret != "" {
+			log.Println("GITHUB_WEBHOOK_SECRET is set but the OAuth server is disabled, webhooks will not be received")
+		}
+	}
+
 	if err := discordBot.Start(); err != nil {
 		log.Fatalf("Failed to start Discord bot: %v", err)
 	}
@@ -55,3 +122,30 @@ func main() {
 	signal.Notify(sc, syscall.SIGINT, syscall.SIGTERM, os.Interrupt)
 	<-sc
 }
+
+// printSchemaVersion prints the applied schema version, followed by any
+// migrations the bot would apply on its next start.
+func printSchemaVersion(cfg *config.Config, keys database.EncryptionKeys) error {
+	var db *database.Database
+	var err error
+	if cfg.DatabaseURL != "" {
+		db, err = database.NewPostgresWithoutMigrating(cfg.DatabaseURL, keys)
+	} else {
+		db, err = database.NewWithoutMigrating(cfg.DatabasePath, keys)
+	}
+	if err != nil {
+		return err
+	}
+	defer db.Close()
+
+	version, pending, err := db.MigrationStatus()
+	if err != nil {
+		return err
+	}
+
+	fmt.Println(version)
+	for _, name := range pending {
+		fmt.Printf("pending: %s\n", name)
+	}
+	return nil
+}